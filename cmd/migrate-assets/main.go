@@ -0,0 +1,99 @@
+// migrate-assets copies the uploaded media between blob store backends, e.g.
+//
+//	go run ./cmd/migrate-assets -from local -to s3
+//
+// Both backends are configured from the same environment as the server.
+// Blobs already in the destination are skipped so the copy can be rerun.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/hashicorp/go-hclog"
+)
+
+func main() {
+	logger := utils.NewLogger()
+	configs := utils.NewConfigurations(logger)
+
+	var from, to string
+	var dryRun, overwrite bool
+	flag.StringVar(&from, "from", "local", "the blob driver to copy from, local or s3")
+	flag.StringVar(&to, "to", "s3", "the blob driver to copy to, local or s3")
+	flag.BoolVar(&dryRun, "dry-run", false, "only list the blobs that would be copied")
+	flag.BoolVar(&overwrite, "overwrite", false, "copy blobs that already exist in the destination")
+	flag.Parse()
+	if from == to {
+		logger.Error("from and to should be different drivers")
+		os.Exit(1)
+	}
+
+	src, err := openStore(logger, configs, from)
+	if err != nil {
+		logger.Error("unable to open the source store", "driver", from, "error", err)
+		os.Exit(1)
+	}
+	dst, err := openStore(logger, configs, to)
+	if err != nil {
+		logger.Error("unable to open the destination store", "driver", to, "error", err)
+		os.Exit(1)
+	}
+
+	// every blob under the prefixes is copied, media documents may miss files
+	// such as originals kept next to their variants
+	ctx := context.Background()
+	copied, skipped, failed := 0, 0, 0
+	for _, prefix := range []string{"assets/", "private/"} {
+		err := src.List(ctx, prefix, func(blob *utils.BlobInfo) error {
+			key := blob.Key
+			if !overwrite {
+				if _, err := dst.Stat(ctx, key); err == nil {
+					skipped++
+					return nil
+				}
+			}
+			if dryRun {
+				logger.Info("would copy", "key", key)
+				copied++
+				return nil
+			}
+			if err := copyBlob(ctx, src, dst, key); err != nil {
+				logger.Error("unable to copy", "key", key, "error", err)
+				failed++
+				return nil
+			}
+			logger.Debug("copied", "key", key)
+			copied++
+			return nil
+		})
+		if err != nil {
+			logger.Error("unable to list the blobs", "prefix", prefix, "error", err)
+			failed++
+		}
+	}
+	logger.Info("migration done", "copied", copied, "skipped", skipped, "failed", failed, "dry_run", dryRun)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func openStore(logger hclog.Logger, configs *utils.Configurations, driver string) (utils.BlobStore, error) {
+	driverConfigs := *configs
+	driverConfigs.BlobDriver = driver
+	return utils.NewBlobStore(logger, &driverConfigs)
+}
+
+func copyBlob(ctx context.Context, src utils.BlobStore, dst utils.BlobStore, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	blob, info, err := src.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+	return dst.Put(ctx, key, blob, info.Size, info.ContentType)
+}
@@ -6,10 +6,12 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"time"
 
 	"github.com/SairamVemula/booksland-backend-go/pkg/models"
 	"github.com/SairamVemula/booksland-backend-go/pkg/routes"
+	"github.com/SairamVemula/booksland-backend-go/pkg/services"
 	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
@@ -18,6 +20,7 @@ import (
 func main() {
 	logger := utils.NewLogger()
 	configs := utils.NewConfigurations(logger)
+	configs.RequireMediaSigningKey(logger)
 
 	// validator contains all the methods that are need to validate the user json in request
 	validator := models.NewValidation()
@@ -28,12 +31,22 @@ func main() {
 	flag.DurationVar(&wait, "graceful-timeout", time.Second*15, "the duration for which the server gracefully wait for existing connections to finish - e.g. 15s or 1m")
 	flag.Parse()
 
-	flag.StringVar(&dir, "dir", "./assets", "the directory to serve files from. Defaults to the current dir")
+	flag.StringVar(&dir, "dir", "", "the directory to serve files from. Defaults to the assets dir of BLOB_LOCAL_DIR")
 	flag.Parse()
 	r := mux.NewRouter()
 
+	// Open the media store first so a misconfigured backend stops the server
+	services.NewMediaStore(logger, configs)
+
 	// This will serve files under http://localhost:8000/static/<filename>
-	r.PathPrefix("/assets/").Handler(http.StripPrefix("/assets/", http.FileServer(http.Dir(dir))))
+	// other blob stores serve their assets themselves
+	if configs.BlobDriver == "local" {
+		// the local store writes the uploads under BLOB_LOCAL_DIR
+		if dir == "" {
+			dir = filepath.Join(configs.BlobLocalDir, "assets")
+		}
+		r.PathPrefix("/assets/").Handler(http.StripPrefix("/assets/", http.FileServer(http.Dir(dir))))
+	}
 
 	//Main routes
 	routes.RegisterUsersRoutes(r, logger, configs, validator)
@@ -44,6 +57,14 @@ func main() {
 	routes.RegisterStocksRoutes(r, logger, configs, validator)
 	routes.RegisterFeedsRoutes(r, logger, configs, validator)
 	routes.RegisterCartRoutes(r, logger, configs, validator)
+	routes.RegisterSearchRoutes(r, logger, configs, validator)
+	routes.RegisterReviewsRoutes(r, logger, configs, validator)
+	routes.RegisterParalinksRoutes(r, logger, configs, validator)
+	routes.RegisterExperimentsRoutes(r, logger, configs, validator)
+
+	// Background jobs
+	services.NewRecommendationService(logger, configs, validator).Start(time.Minute * time.Duration(configs.RecommendationInterval))
+	services.NewMediaGCService(logger, configs, validator).Start(time.Minute * time.Duration(configs.MediaGCInterval))
 
 	srv := &http.Server{
 		Addr: configs.ServerAddress,
@@ -60,8 +81,10 @@ func main() {
 				http.MethodPut,
 				http.MethodPatch,
 				http.MethodDelete,
+				http.MethodOptions,
 			},
-			AllowedHeaders:   []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization"},
+			AllowedHeaders:   []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "X-Client-ID", "Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Metadata"},
+			ExposedHeaders:   []string{"Location", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size", "Upload-Offset", "Upload-Length", "X-Media-ID"},
 			AllowCredentials: true,
 		}).Handler(r), // Pass our instance of gorilla/mux in.
 
@@ -7,6 +7,7 @@ import (
 	"github.com/SairamVemula/booksland-backend-go/pkg/models"
 	"github.com/SairamVemula/booksland-backend-go/pkg/services"
 	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/gorilla/mux"
 	"github.com/hashicorp/go-hclog"
 )
 
@@ -81,16 +82,98 @@ func (ac *AuthController) Login(w http.ResponseWriter, r *http.Request) {
 
 }
 func (ac *AuthController) ResentVerification(w http.ResponseWriter, r *http.Request) {
-
+	resend := &models.ResendVerification{}
+	perr := utils.ParseBody(r, resend)
+	if perr != nil {
+		utils.ResponseStringError(&w, perr.Error())
+		return
+	}
+	err := ac.validator.Struct(resend)
+	if err != nil {
+		utils.ResponseValidationError(&w, &err)
+		return
+	}
+	ac.authService.ResendVerification(r.Context(), resend.Email)
+	utils.ResponseSuccess(&w, &utils.Response{Code: 200, Message: "if the email is registered and not verified, a code has been sent"})
 }
 func (ac *AuthController) Verify(w http.ResponseWriter, r *http.Request) {
+	verify := &models.VerifyEmail{}
+	perr := utils.ParseBody(r, verify)
+	if perr != nil {
+		utils.ResponseStringError(&w, perr.Error())
+		return
+	}
+	err := ac.validator.Struct(verify)
+	if err != nil {
+		utils.ResponseValidationError(&w, &err)
+		return
+	}
+	e := ac.authService.VerifyEmail(r.Context(), verify.Email, verify.Code)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, &utils.Response{Code: 200, Message: "email verified"})
+}
 
+// SendPhoneOtp texts a verification code to the phone of the user
+func (ac *AuthController) SendPhoneOtp(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	e := ac.authService.SendPhoneOtp(r.Context(), authUser)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, &utils.Response{Code: 200, Message: "a code has been sent to your phone"})
 }
-func (ac *AuthController) ForgotPassword(w http.ResponseWriter, r *http.Request) {
 
+func (ac *AuthController) VerifyPhone(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	verify := &models.VerifyPhone{}
+	perr := utils.ParseBody(r, verify)
+	if perr != nil {
+		utils.ResponseStringError(&w, perr.Error())
+		return
+	}
+	err := ac.validator.Struct(verify)
+	if err != nil {
+		utils.ResponseValidationError(&w, &err)
+		return
+	}
+	e := ac.authService.VerifyPhone(r.Context(), authUser, verify.Code)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, &utils.Response{Code: 200, Message: "phone verified"})
 }
-func (ac *AuthController) ResetPassword(w http.ResponseWriter, r *http.Request) {
 
+func (ac *AuthController) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	forgot := &models.ForgotPassword{Email: mux.Vars(r)["email"]}
+	err := ac.validator.Struct(forgot)
+	if err != nil {
+		utils.ResponseValidationError(&w, &err)
+		return
+	}
+	ac.authService.ForgotPassword(forgot.Email)
+	utils.ResponseSuccess(&w, &utils.Response{Code: 200, Message: "if the email is registered, a reset mail has been sent"})
+}
+func (ac *AuthController) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	reset := &models.ResetPassword{}
+	perr := utils.ParseBody(r, reset)
+	if perr != nil {
+		utils.ResponseStringError(&w, perr.Error())
+		return
+	}
+	err := ac.validator.Struct(reset)
+	if err != nil {
+		utils.ResponseValidationError(&w, &err)
+		return
+	}
+	e := ac.authService.ResetPassword(r.Context(), reset.Token, reset.Password)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, &utils.Response{Code: 200, Message: "password changed, please login again"})
 }
 
 type TokenResponse struct {
@@ -54,6 +54,9 @@ func (bc *BookController) Get(w http.ResponseWriter, r *http.Request, authUser *
 		return
 	}
 	services.NewGetQuery(&query)
+	if authUser != nil {
+		query.UserID = authUser.ID
+	}
 
 	res, e := bc.bookService.Find(r.Context(), &query)
 	if e != nil {
@@ -114,3 +117,23 @@ func (bc *BookController) Delete(w http.ResponseWriter, r *http.Request, authUse
 	utils.ResponseSuccess(&w, utils.Response{Code: 200, Message: "success"})
 
 }
+
+func (bc *BookController) Reorder(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	ids := &models.Reorder{}
+	err := utils.ParseBody(r, ids)
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return
+	}
+	e := bc.validator.Struct(ids)
+	if e != nil {
+		utils.ResponseValidationError(&w, &e)
+		return
+	}
+	res, error := bc.bookService.Reorder(r.Context(), ids)
+	if error != nil {
+		utils.ResponseError(&w, error)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
@@ -51,6 +51,9 @@ func (cic *CartItemController) Get(w http.ResponseWriter, r *http.Request, authU
 		return
 	}
 	services.NewGetQuery(&query)
+	if authUser != nil {
+		query.UserID = authUser.ID
+	}
 
 	res, e := cic.cartItemService.Find(r.Context(), &query)
 	if e != nil {
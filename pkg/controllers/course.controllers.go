@@ -58,6 +58,9 @@ func (cc *CourseController) GetCourse(w http.ResponseWriter, r *http.Request, au
 		return
 	}
 	services.NewGetQuery(&query)
+	if authUser != nil {
+		query.UserID = authUser.ID
+	}
 
 	res, e := cc.courseService.Find(r.Context(), &query)
 	if e != nil {
@@ -116,3 +119,23 @@ func (cc *CourseController) DeleteCourse(w http.ResponseWriter, r *http.Request,
 	utils.ResponseSuccess(&w, utils.Response{Code: 200, Message: "success"})
 
 }
+
+func (cc *CourseController) ReorderCourses(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	ids := &models.Reorder{}
+	err := utils.ParseBody(r, ids)
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return
+	}
+	e := cc.validator.Struct(ids)
+	if e != nil {
+		utils.ResponseValidationError(&w, &e)
+		return
+	}
+	res, error := cc.courseService.Reorder(r.Context(), ids)
+	if error != nil {
+		utils.ResponseError(&w, error)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
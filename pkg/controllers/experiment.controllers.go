@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/services"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+type ExperimentController struct {
+	experimentService *services.ExperimentService
+	logger            hclog.Logger
+	configs           *utils.Configurations
+	validator         *models.Validation
+}
+
+func NewExperimentController(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *ExperimentController {
+	return &ExperimentController{services.NewExperimentService(logger, configs, validator), logger, configs, validator}
+}
+
+func (ec *ExperimentController) Create(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	experiment := &models.Experiment{}
+	err := utils.ParseBody(r, experiment)
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return
+	}
+	experiment.CreatedBy = authUser.ID
+	e := ec.validator.Struct(experiment)
+	if e != nil {
+		utils.ResponseValidationError(&w, &e)
+		return
+	}
+	res, error := ec.experimentService.Create(r.Context(), experiment)
+	if error != nil {
+		utils.ResponseError(&w, error)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (ec *ExperimentController) Get(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	var query services.GetQuery
+	err := decoder.Decode(&query, r.URL.Query())
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return
+	}
+	services.NewGetQuery(&query)
+
+	res, e := ec.experimentService.Find(r.Context(), &query)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (ec *ExperimentController) GetById(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	params := mux.Vars(r)
+	if params["experiment_id"] == "" {
+		utils.ResponseStringError(&w, "experiment_id is required")
+		return
+	}
+	res, e := ec.experimentService.FindById(r.Context(), params["experiment_id"])
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (ec *ExperimentController) Update(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	params := mux.Vars(r)
+	if params["experiment_id"] == "" {
+		utils.ResponseStringError(&w, "experiment_id is required")
+		return
+	}
+	experiment := &models.UpdateExperiment{}
+	err := utils.ParseBody(r, experiment)
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return
+	}
+	e := ec.validator.Struct(experiment)
+	if e != nil {
+		utils.ResponseValidationError(&w, &e)
+		return
+	}
+	res, error := ec.experimentService.UpdateById(r.Context(), params["experiment_id"], experiment)
+	if error != nil {
+		utils.ResponseError(&w, error)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (ec *ExperimentController) Delete(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	params := mux.Vars(r)
+	if params["experiment_id"] == "" {
+		utils.ResponseStringError(&w, "experiment_id is required")
+		return
+	}
+	e := ec.experimentService.DeleteById(r.Context(), params["experiment_id"])
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, utils.Response{Code: 200, Message: "success"})
+}
+
+func (ec *ExperimentController) Report(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	params := mux.Vars(r)
+	if params["experiment_id"] == "" {
+		utils.ResponseStringError(&w, "experiment_id is required")
+		return
+	}
+	res, e := ec.experimentService.Report(r.Context(), params["experiment_id"])
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/SairamVemula/booksland-backend-go/pkg/models"
 	"github.com/SairamVemula/booksland-backend-go/pkg/services"
@@ -11,14 +12,27 @@ import (
 )
 
 type FeedController struct {
-	feedService *services.FeedService
-	logger      hclog.Logger
-	configs     *utils.Configurations
-	validator   *models.Validation
+	feedService       *services.FeedService
+	experimentService *services.ExperimentService
+	logger            hclog.Logger
+	configs           *utils.Configurations
+	validator         *models.Validation
 }
 
 func NewFeedController(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *FeedController {
-	return &FeedController{services.NewFeedService(logger, configs, validator), logger, configs, validator}
+	return &FeedController{services.NewFeedService(logger, configs, validator), services.NewExperimentService(logger, configs, validator), logger, configs, validator}
+}
+
+// experimentSubject is who the experiment variants are assigned to, users are
+// bucketed by their id, anonymous clients by the id they send
+func experimentSubject(r *http.Request, clientID string, authUser *models.User) string {
+	if authUser != nil {
+		return authUser.ID.Hex()
+	}
+	if subject := r.Header.Get("X-Client-ID"); subject != "" {
+		return subject
+	}
+	return clientID
 }
 
 func (fc *FeedController) Create(w http.ResponseWriter, r *http.Request, authUser *models.User) {
@@ -29,6 +43,7 @@ func (fc *FeedController) Create(w http.ResponseWriter, r *http.Request, authUse
 		return
 	}
 	models.NewFeed(feed)
+	feed.CreatedBy = authUser.ID
 	e := fc.validator.Struct(feed)
 	if e != nil {
 		utils.ResponseValidationError(&w, &e)
@@ -50,6 +65,17 @@ func (fc *FeedController) Get(w http.ResponseWriter, r *http.Request, authUser *
 		return
 	}
 	services.NewGetQuery(&query)
+	if authUser != nil {
+		query.UserID = authUser.ID
+	}
+	// only admins can see drafts, archived and scheduled feeds
+	if authUser == nil || authUser.Type != "admin" {
+		query.Status = services.FEED_STATUS_LIVE
+		if e := fc.experimentService.Assign(r.Context(), &query, experimentSubject(r, query.ClientID, authUser)); e != nil {
+			utils.ResponseError(&w, e)
+			return
+		}
+	}
 
 	res, e := fc.feedService.Find(r.Context(), &query)
 	if e != nil {
@@ -68,6 +94,10 @@ func (fc *FeedController) GetById(w http.ResponseWriter, r *http.Request, authUs
 		utils.ResponseError(&w, e)
 		return
 	}
+	if (authUser == nil || authUser.Type != "admin") && !res.IsLive(time.Now().UnixMilli()) {
+		utils.ResponseError(&w, utils.NotFound("feed not found."))
+		return
+	}
 	utils.ResponseSuccess(&w, res)
 
 }
@@ -106,3 +136,27 @@ func (fc *FeedController) Delete(w http.ResponseWriter, r *http.Request, authUse
 	utils.ResponseSuccess(&w, utils.Response{Code: 200, Message: "success"})
 
 }
+
+func (fc *FeedController) CacheStats(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	utils.ResponseSuccess(&w, fc.feedService.CacheStats())
+}
+
+func (fc *FeedController) Reorder(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	ids := &models.Reorder{}
+	err := utils.ParseBody(r, ids)
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return
+	}
+	e := fc.validator.Struct(ids)
+	if e != nil {
+		utils.ResponseValidationError(&w, &e)
+		return
+	}
+	res, error := fc.feedService.Reorder(r.Context(), ids)
+	if error != nil {
+		utils.ResponseError(&w, error)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/services"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+type FeedVersionController struct {
+	feedService        *services.FeedService
+	feedVersionService *services.FeedVersionService
+	logger             hclog.Logger
+	configs            *utils.Configurations
+	validator          *models.Validation
+}
+
+func NewFeedVersionController(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *FeedVersionController {
+	return &FeedVersionController{
+		services.NewFeedService(logger, configs, validator),
+		services.NewFeedVersionService(logger, configs, validator),
+		logger, configs, validator,
+	}
+}
+
+// versionParams reads the feed_id and version path variables
+func versionParams(r *http.Request) (string, int, string) {
+	params := mux.Vars(r)
+	if params["feed_id"] == "" {
+		return "", 0, "feed_id is required"
+	}
+	version, err := strconv.Atoi(params["version"])
+	if err != nil || version <= 0 {
+		return "", 0, "version should be a positive number"
+	}
+	return params["feed_id"], version, ""
+}
+
+func (fvc *FeedVersionController) Get(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	feed_id := mux.Vars(r)["feed_id"]
+	if feed_id == "" {
+		utils.ResponseStringError(&w, "feed_id is required")
+		return
+	}
+	var query services.GetQuery
+	err := decoder.Decode(&query, r.URL.Query())
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return
+	}
+	services.NewGetQuery(&query)
+
+	res, e := fvc.feedVersionService.Find(r.Context(), feed_id, &query)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (fvc *FeedVersionController) Create(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	feed_id := mux.Vars(r)["feed_id"]
+	if feed_id == "" {
+		utils.ResponseStringError(&w, "feed_id is required")
+		return
+	}
+	feed := &models.Feed{}
+	err := utils.ParseBody(r, feed)
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return
+	}
+	e := fvc.validator.Struct(feed)
+	if e != nil {
+		utils.ResponseValidationError(&w, &e)
+		return
+	}
+	feed.CreatedBy = authUser.ID
+	res, error := fvc.feedService.CreateDraft(r.Context(), feed_id, feed, authUser.ID)
+	if error != nil {
+		utils.ResponseError(&w, error)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (fvc *FeedVersionController) Preview(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	feed_id, version, message := versionParams(r)
+	if message != "" {
+		utils.ResponseStringError(&w, message)
+		return
+	}
+	res, e := fvc.feedService.Preview(r.Context(), feed_id, version)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (fvc *FeedVersionController) Rollback(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	feed_id, version, message := versionParams(r)
+	if message != "" {
+		utils.ResponseStringError(&w, message)
+		return
+	}
+	res, e := fvc.feedService.Rollback(r.Context(), feed_id, version, authUser.ID)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
@@ -2,6 +2,9 @@ package controllers
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/SairamVemula/booksland-backend-go/pkg/models"
 	"github.com/SairamVemula/booksland-backend-go/pkg/services"
@@ -11,53 +14,62 @@ import (
 )
 
 type MediaController struct {
-	mediaService *services.MediaService
-	logger       hclog.Logger
-	configs      *utils.Configurations
-	validator    *models.Validation
+	mediaService   *services.MediaService
+	mediaGCService *services.MediaGCService
+	logger         hclog.Logger
+	configs        *utils.Configurations
+	validator      *models.Validation
 }
 
 func NewMediaController(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *MediaController {
-	return &MediaController{services.NewMediaService(logger, configs, validator), logger, configs, validator}
+	return &MediaController{services.NewMediaService(logger, configs, validator), services.NewMediaGCService(logger, configs, validator), logger, configs, validator}
 }
 
 func (mdc *MediaController) Create(w http.ResponseWriter, r *http.Request, authUser *models.User) {
-	path, err := utils.UploadFile(r, "file")
-	if err != nil || path == "empty" {
-		utils.ResponseStringError(&w, err.Error())
-		return
+	// the form is read before the upload to know where the file goes, only
+	// admins can upload public media
+	err := utils.LimitBody(w, r)
+	private := authUser.Type != "admin" || (err == nil && r.FormValue("private") == "true")
+	dir, seen := utils.ASSETS_DIR, utils.HashLookup(func(hash string) bool { return mdc.mediaService.HasHash(r.Context(), hash) })
+	if private {
+		dir, seen = utils.PRIVATE_DIR, nil
 	}
-	if path == "empty" {
-		utils.ResponseStringError(&w, "File is required")
+	var uploaded *utils.UploadedFile
+	if err == nil {
+		uploaded, err = utils.UploadFile(w, r, "file", mdc.mediaService.Store(), dir, seen)
+	}
+	if err == utils.ErrUploadTooLarge {
+		utils.ResponseError(&w, &utils.RestError{Code: http.StatusRequestEntityTooLarge, Message: err.Error(), Error: "request entity too large"})
 		return
 	}
-	if err := r.ParseForm(); err != nil {
+	if err != nil {
 		utils.ResponseStringError(&w, err.Error())
 		return
 	}
-	media := &models.Media{Path: path, CreatedBy: authUser.ID}
-	media = models.NewMedia(media)
-	e := mdc.validator.Struct(media)
-	if e != nil {
-		utils.ResponseValidationError(&w, &e)
+	if uploaded == nil {
+		utils.ResponseStringError(&w, "File is required")
 		return
 	}
-	res, error := mdc.mediaService.Create(r.Context(), media)
-	if error != nil {
-		utils.ResponseError(&w, error)
+	res, e := mdc.mediaService.CreateFromUpload(r.Context(), uploaded, r.FormValue("alt"), private, authUser.ID)
+	if e != nil {
+		utils.ResponseError(&w, e)
 		return
 	}
 	utils.ResponseSuccess(&w, res)
 }
 
-func (mdc *MediaController) Get(w http.ResponseWriter, r *http.Request /**, authUser *models.User) **/) {
-	var query services.GetQuery
+func (mdc *MediaController) Get(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	var query services.MediaQuery
 	err := decoder.Decode(&query, r.URL.Query())
 	if err != nil {
 		utils.ResponseStringError(&w, err.Error())
 		return
 	}
-	services.NewGetQuery(&query)
+	services.NewGetQuery(&query.GetQuery)
+	if query.Private && (authUser == nil || authUser.Type != "admin") {
+		utils.ResponseError(&w, &utils.RestError{Code: http.StatusForbidden, Message: "Forbbiden Access", Error: "forbidden"})
+		return
+	}
 
 	res, e := mdc.mediaService.Find(r.Context(), &query)
 	if e != nil {
@@ -66,7 +78,7 @@ func (mdc *MediaController) Get(w http.ResponseWriter, r *http.Request /**, auth
 	}
 	utils.ResponseSuccess(&w, res)
 }
-func (mdc *MediaController) GetById(w http.ResponseWriter, r *http.Request /**, authUser *models.User) **/) {
+func (mdc *MediaController) GetById(w http.ResponseWriter, r *http.Request, authUser *models.User) {
 	media_id := mux.Vars(r)["media_id"]
 	if media_id == "" {
 		utils.ResponseStringError(&w, "media_id is required")
@@ -77,17 +89,113 @@ func (mdc *MediaController) GetById(w http.ResponseWriter, r *http.Request /**,
 		utils.ResponseError(&w, e)
 		return
 	}
+	if e := mdc.mediaService.CanOpen(res, r.URL.Query(), authUser); e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	if res.Private {
+		w.Header().Set("Cache-Control", "private, no-store")
+	}
+	path := res.Path
+	if size := r.URL.Query().Get("size"); size != "" {
+		if !utils.IsImageSize(size) {
+			utils.ResponseStringError(&w, "size should be one of thumb, card, full")
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "jpeg"
+			if strings.Contains(r.Header.Get("Accept"), "image/webp") {
+				format = "webp"
+			}
+			w.Header().Add("Vary", "Accept")
+		}
+		if format != "webp" && format != "jpeg" {
+			utils.ResponseStringError(&w, "format should be one of webp, jpeg")
+			return
+		}
+		// builds without cgo only write jpeg variants, webp asks get those
+		variant := res.Variant(size, format)
+		if variant == nil && format == "webp" {
+			variant = res.Variant(size, "jpeg")
+		}
+		// media uploaded before variants existed only have the original
+		if variant != nil {
+			path = variant.Path
+		}
+	}
 	// data, err := os.ReadFile("."+res.Path);
 	// if err != nil {
 	// 	utils.ResponseStringError(&w, err.Error())
 	// 	return
 	// }
 	// w.Write(data)
-	http.ServeFile(w, r, "."+res.Path)
+	blob, info, e := mdc.mediaService.Open(r.Context(), path)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	defer blob.Close()
+	if info.ContentType != "" {
+		w.Header().Set("Content-Type", info.ContentType)
+	}
+	http.ServeContent(w, r, path, info.ModTime, blob)
 	// utils.ResponseSuccess(&w, res)
 }
 
-func (mdc *MediaController) Delete(w http.ResponseWriter, r *http.Request /**, authUser *models.User) **/) {
+// Sign returns an expiring url of a private media
+func (mdc *MediaController) Sign(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	params := mux.Vars(r)
+	if params["media_id"] == "" {
+		utils.ResponseStringError(&w, "media_id is required")
+		return
+	}
+	sign := &models.SignMedia{}
+	if r.ContentLength != 0 {
+		if err := utils.ParseBody(r, sign); err != nil {
+			utils.ResponseStringError(&w, err.Error())
+			return
+		}
+	}
+	e := mdc.validator.Struct(sign)
+	if e != nil {
+		utils.ResponseValidationError(&w, &e)
+		return
+	}
+	res, error := mdc.mediaService.SignUrl(r.Context(), params["media_id"], sign, authUser)
+	if error != nil {
+		utils.ResponseError(&w, error)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (mdc *MediaController) Update(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	params := mux.Vars(r)
+	if params["media_id"] == "" {
+		utils.ResponseStringError(&w, "media_id is required")
+		return
+	}
+	media := &models.UpdateMedia{}
+	err := utils.ParseBody(r, media)
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return
+	}
+	e := mdc.validator.Struct(media)
+	if e != nil {
+		utils.ResponseValidationError(&w, &e)
+		return
+	}
+	res, error := mdc.mediaService.UpdateById(r.Context(), params["media_id"], media)
+	if error != nil {
+		utils.ResponseError(&w, error)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (mdc *MediaController) Delete(w http.ResponseWriter, r *http.Request, authUser *models.User) {
 	params := mux.Vars(r)
 	if params["media_id"] == "" {
 		utils.ResponseStringError(&w, "media_id is required")
@@ -101,3 +209,56 @@ func (mdc *MediaController) Delete(w http.ResponseWriter, r *http.Request /**, a
 	utils.ResponseSuccess(&w, utils.Response{Code: 200, Message: "success"})
 
 }
+
+// gcGrace reads the grace query param in hours, MEDIA_GC_GRACE_PERIOD by default
+func (mdc *MediaController) gcGrace(r *http.Request) (time.Duration, string) {
+	hours := mdc.configs.MediaGCGracePeriod
+	if grace := r.URL.Query().Get("grace"); grace != "" {
+		value, err := strconv.Atoi(grace)
+		if err != nil || value < 0 {
+			return 0, "grace should be a number of hours"
+		}
+		hours = value
+	}
+	return time.Hour * time.Duration(hours), ""
+}
+
+// Orphans reports the unreferenced media and unowned files without deleting them
+func (mdc *MediaController) Orphans(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	grace, message := mdc.gcGrace(r)
+	if message != "" {
+		utils.ResponseStringError(&w, message)
+		return
+	}
+	res, e := mdc.mediaGCService.Report(r.Context(), grace)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+// Collect deletes the orphans, only reporting them when dry_run is true
+func (mdc *MediaController) Collect(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	grace, message := mdc.gcGrace(r)
+	if message != "" {
+		utils.ResponseStringError(&w, message)
+		return
+	}
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.ResponseStringError(&w, "dry_run should be true or false")
+			return
+		}
+		dryRun = parsed
+	}
+	res, e := mdc.mediaGCService.Collect(r.Context(), dryRun, grace)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	mdc.logger.Info("media garbage collected", "user", authUser.ID.Hex(), "dry_run", dryRun, "deleted", res.Deleted)
+	utils.ResponseSuccess(&w, res)
+}
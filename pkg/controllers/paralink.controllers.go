@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/services"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ParalinkController struct {
+	paralinkService *services.ParalinkService
+	logger          hclog.Logger
+	configs         *utils.Configurations
+	validator       *models.Validation
+}
+
+func NewParalinkController(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *ParalinkController {
+	return &ParalinkController{services.NewParalinkService(logger, configs, validator), logger, configs, validator}
+}
+
+func (pc *ParalinkController) Create(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	paralink := &models.Paralink{}
+	err := utils.ParseBody(r, paralink)
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return
+	}
+	paralink.CreatedBy = authUser.ID
+	e := pc.validator.Struct(paralink)
+	if e != nil {
+		utils.ResponseValidationError(&w, &e)
+		return
+	}
+	res, error := pc.paralinkService.Create(r.Context(), paralink)
+	if error != nil {
+		utils.ResponseError(&w, error)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (pc *ParalinkController) Get(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	var query services.GetQuery
+	err := decoder.Decode(&query, r.URL.Query())
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return
+	}
+	services.NewGetQuery(&query)
+
+	res, e := pc.paralinkService.Find(r.Context(), &query)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (pc *ParalinkController) Resolve(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	params := mux.Vars(r)
+	if params["paralink"] == "" {
+		utils.ResponseStringError(&w, "paralink is required")
+		return
+	}
+	var user_id primitive.ObjectID
+	if authUser != nil {
+		user_id = authUser.ID
+	}
+	res, e := pc.paralinkService.Resolve(r.Context(), params["paralink"], user_id, experimentSubject(r, r.URL.Query().Get("client_id"), authUser))
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (pc *ParalinkController) Update(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	params := mux.Vars(r)
+	if params["paralink"] == "" {
+		utils.ResponseStringError(&w, "paralink is required")
+		return
+	}
+	paralink := &models.UpdateParalink{}
+	err := utils.ParseBody(r, paralink)
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return
+	}
+	e := pc.validator.Struct(paralink)
+	if e != nil {
+		utils.ResponseValidationError(&w, &e)
+		return
+	}
+	res, error := pc.paralinkService.UpdateBySlug(r.Context(), params["paralink"], paralink)
+	if error != nil {
+		utils.ResponseError(&w, error)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (pc *ParalinkController) Delete(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	params := mux.Vars(r)
+	if params["paralink"] == "" {
+		utils.ResponseStringError(&w, "paralink is required")
+		return
+	}
+	e := pc.paralinkService.DeleteBySlug(r.Context(), params["paralink"])
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, utils.Response{Code: 200, Message: "success"})
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/services"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+type RecommendationController struct {
+	recommendationService *services.RecommendationService
+	logger                hclog.Logger
+	configs               *utils.Configurations
+	validator             *models.Validation
+}
+
+func NewRecommendationController(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *RecommendationController {
+	return &RecommendationController{services.NewRecommendationService(logger, configs, validator), logger, configs, validator}
+}
+
+func (rc *RecommendationController) GetByBook(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	book_id := mux.Vars(r)["book_id"]
+	if book_id == "" {
+		utils.ResponseStringError(&w, "book_id is required")
+		return
+	}
+	var limit int64 = 10
+	if r.URL.Query().Get("limit") != "" {
+		value, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || value <= 0 {
+			utils.ResponseStringError(&w, "limit should be a positive number")
+			return
+		}
+		limit = int64(value)
+	}
+	if limit > services.MAX_LIMIT {
+		limit = services.MAX_LIMIT
+	}
+	res, e := rc.recommendationService.FindByBook(r.Context(), book_id, limit)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/services"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+type ReviewController struct {
+	reviewService *services.ReviewService
+	logger        hclog.Logger
+	configs       *utils.Configurations
+	validator     *models.Validation
+}
+
+func NewReviewController(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *ReviewController {
+	return &ReviewController{services.NewReviewService(logger, configs, validator), logger, configs, validator}
+}
+
+func (rc *ReviewController) Create(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	book_id := mux.Vars(r)["book_id"]
+	if book_id == "" {
+		utils.ResponseStringError(&w, "book_id is required")
+		return
+	}
+	review := &models.Review{}
+	err := utils.ParseBody(r, review)
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return
+	}
+	e := rc.validator.Struct(review)
+	if e != nil {
+		utils.ResponseValidationError(&w, &e)
+		return
+	}
+	res, error := rc.reviewService.Create(r.Context(), book_id, authUser, review)
+	if error != nil {
+		utils.ResponseError(&w, error)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (rc *ReviewController) Get(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	book_id := mux.Vars(r)["book_id"]
+	if book_id == "" {
+		utils.ResponseStringError(&w, "book_id is required")
+		return
+	}
+	var query services.GetQuery
+	err := decoder.Decode(&query, r.URL.Query())
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return
+	}
+	services.NewGetQuery(&query)
+
+	res, e := rc.reviewService.Find(r.Context(), book_id, &query, authUser != nil && authUser.Type == "admin")
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (rc *ReviewController) Update(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	params := mux.Vars(r)
+	if params["review_id"] == "" {
+		utils.ResponseStringError(&w, "review_id is required")
+		return
+	}
+	review := &models.UpdateReview{}
+	err := utils.ParseBody(r, review)
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return
+	}
+	e := rc.validator.Struct(review)
+	if e != nil {
+		utils.ResponseValidationError(&w, &e)
+		return
+	}
+	res, error := rc.reviewService.UpdateById(r.Context(), params["review_id"], authUser, review)
+	if error != nil {
+		utils.ResponseError(&w, error)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (rc *ReviewController) SetStatus(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	params := mux.Vars(r)
+	if params["review_id"] == "" {
+		utils.ResponseStringError(&w, "review_id is required")
+		return
+	}
+	status := &models.ReviewStatus{}
+	err := utils.ParseBody(r, status)
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return
+	}
+	e := rc.validator.Struct(status)
+	if e != nil {
+		utils.ResponseValidationError(&w, &e)
+		return
+	}
+	res, error := rc.reviewService.SetStatus(r.Context(), params["review_id"], status)
+	if error != nil {
+		utils.ResponseError(&w, error)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (rc *ReviewController) Delete(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	params := mux.Vars(r)
+	if params["review_id"] == "" {
+		utils.ResponseStringError(&w, "review_id is required")
+		return
+	}
+	e := rc.reviewService.DeleteById(r.Context(), params["review_id"], authUser)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, utils.Response{Code: 200, Message: "success"})
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/services"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/hashicorp/go-hclog"
+)
+
+type SearchController struct {
+	searchService    *services.SearchService
+	searchLogService *services.SearchLogService
+	logger           hclog.Logger
+	configs          *utils.Configurations
+	validator        *models.Validation
+}
+
+func NewSearchController(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *SearchController {
+	return &SearchController{services.NewSearchService(logger, configs, validator), services.NewSearchLogService(logger, configs, validator), logger, configs, validator}
+}
+
+func (sc *SearchController) Suggest(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	var query services.SuggestQuery
+	err := decoder.Decode(&query, r.URL.Query())
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return
+	}
+	services.NewSuggestQuery(&query)
+
+	res, e := sc.searchService.Suggest(r.Context(), &query)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (sc *SearchController) Rebuild(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	e := sc.searchService.Rebuild(r.Context())
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, utils.Response{Code: 200, Message: "success"})
+}
+
+func (sc *SearchController) analyticsQuery(w http.ResponseWriter, r *http.Request) (*services.AnalyticsQuery, bool) {
+	var query services.AnalyticsQuery
+	err := decoder.Decode(&query, r.URL.Query())
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return nil, false
+	}
+	services.NewAnalyticsQuery(&query)
+	if query.From > query.To {
+		utils.ResponseStringError(&w, "from should be before to")
+		return nil, false
+	}
+	return &query, true
+}
+
+func (sc *SearchController) TopQueries(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	query, ok := sc.analyticsQuery(w, r)
+	if !ok {
+		return
+	}
+	res, e := sc.searchLogService.TopQueries(r.Context(), query)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (sc *SearchController) ZeroResultQueries(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	query, ok := sc.analyticsQuery(w, r)
+	if !ok {
+		return
+	}
+	res, e := sc.searchLogService.ZeroResultQueries(r.Context(), query)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
+
+func (sc *SearchController) Trends(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	query, ok := sc.analyticsQuery(w, r)
+	if !ok {
+		return
+	}
+	res, e := sc.searchLogService.Trends(r.Context(), query)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	utils.ResponseSuccess(&w, res)
+}
@@ -51,6 +51,9 @@ func (sc *StockController) Get(w http.ResponseWriter, r *http.Request, authUser
 		return
 	}
 	services.NewGetQuery(&query)
+	if authUser != nil {
+		query.UserID = authUser.ID
+	}
 
 	res, e := sc.stockService.Find(r.Context(), &query)
 	if e != nil {
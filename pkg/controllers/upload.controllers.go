@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/services"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+// UploadController serves the tus resumable upload protocol
+type UploadController struct {
+	uploadService *services.UploadService
+	logger        hclog.Logger
+	configs       *utils.Configurations
+	validator     *models.Validation
+}
+
+func NewUploadController(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *UploadController {
+	return &UploadController{services.NewUploadService(logger, configs, validator), logger, configs, validator}
+}
+
+// tusHeaders sets the headers every tus response carries
+func (uc *UploadController) tusHeaders(w http.ResponseWriter, upload *models.Upload) {
+	w.Header().Set("Tus-Resumable", services.TUS_VERSION)
+	w.Header().Set("Cache-Control", "no-store")
+	if upload == nil {
+		return
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if !upload.MediaID.IsZero() {
+		w.Header().Set("X-Media-ID", upload.MediaID.Hex())
+	}
+}
+
+// checkVersion rejects clients speaking another version of the protocol
+func (uc *UploadController) checkVersion(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Tus-Resumable") != services.TUS_VERSION {
+		w.Header().Set("Tus-Version", services.TUS_VERSION)
+		utils.ResponseError(&w, &utils.RestError{Code: http.StatusPreconditionFailed, Message: "Tus-Resumable should be " + services.TUS_VERSION, Error: "precondition failed"})
+		return false
+	}
+	return true
+}
+
+func (uc *UploadController) Options(w http.ResponseWriter, r *http.Request) {
+	uc.tusHeaders(w, nil)
+	w.Header().Set("Tus-Version", services.TUS_VERSION)
+	w.Header().Set("Tus-Extension", "creation,termination")
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(uc.configs.UploadMaxSize, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (uc *UploadController) Create(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	uc.tusHeaders(w, nil)
+	if !uc.checkVersion(w, r) {
+		return
+	}
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil {
+		utils.ResponseStringError(&w, "Upload-Length is required")
+		return
+	}
+	metadata, err := services.ParseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		utils.ResponseStringError(&w, err.Error())
+		return
+	}
+	// only admins can upload public media
+	if authUser.Type != "admin" {
+		metadata["private"] = "true"
+	}
+	upload, e := uc.uploadService.Create(r.Context(), length, metadata, authUser.ID)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	uc.tusHeaders(w, upload)
+	w.Header().Set("Location", "/media/uploads/"+upload.ID.Hex())
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (uc *UploadController) Head(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	uc.tusHeaders(w, nil)
+	upload, e := uc.uploadService.FindById(r.Context(), mux.Vars(r)["upload_id"], authUser.ID)
+	if e != nil {
+		w.WriteHeader(e.Code)
+		return
+	}
+	uc.tusHeaders(w, upload)
+	if upload.Status == models.UploadStatusFailed {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (uc *UploadController) Patch(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	uc.tusHeaders(w, nil)
+	if !uc.checkVersion(w, r) {
+		return
+	}
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		utils.ResponseError(&w, &utils.RestError{Code: http.StatusUnsupportedMediaType, Message: "Content-Type should be application/offset+octet-stream", Error: "unsupported media type"})
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		utils.ResponseStringError(&w, "Upload-Offset is required")
+		return
+	}
+	upload, e := uc.uploadService.WriteChunk(r.Context(), mux.Vars(r)["upload_id"], offset, r.Body, authUser.ID)
+	if e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	uc.tusHeaders(w, upload)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (uc *UploadController) Delete(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+	uc.tusHeaders(w, nil)
+	if !uc.checkVersion(w, r) {
+		return
+	}
+	if e := uc.uploadService.Terminate(r.Context(), mux.Vars(r)["upload_id"], authUser.ID); e != nil {
+		utils.ResponseError(&w, e)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -77,6 +77,9 @@ func (uc *UserController) GetUsers(w http.ResponseWriter, r *http.Request, authU
 		}
 		page = int64(value)
 	}
+	if limit > services.MAX_LIMIT {
+		limit = services.MAX_LIMIT
+	}
 
 	res, e := uc.userService.Find(r.Context(), page, limit)
 	if e != nil {
@@ -0,0 +1,20 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+)
+
+// RequireVerifiedEmail wraps the checkout handlers, with REQUIRE_VERIFIED_EMAIL
+// set the users who have not verified their email are turned away
+func (m *Middleware) RequireVerifiedEmail(handler AuthenticatedHandler) AuthenticatedHandler {
+	return func(w http.ResponseWriter, r *http.Request, authUser *models.User) {
+		if m.configs.RequireVerifiedEmail && authUser != nil && authUser.Type != "admin" && !authUser.Verified.Email {
+			utils.ResponseError(&w, &utils.RestError{Code: http.StatusForbidden, Message: "Please verify your email before checking out", Error: "email not verified"})
+			return
+		}
+		handler(w, r, authUser)
+	}
+}
@@ -14,6 +14,7 @@ type Book struct {
 	Tags       []string           `json:"tags,omitempty" bson:"tags,omitempty"`
 	Image      primitive.ObjectID `json:"image,omitempty" bson:"image,omitempty"`
 	Order      int                `json:"order,omitempty" bson:"order,omitempty"`
+	Rating     *Rating            `json:"rating,omitempty" bson:"rating,omitempty"`
 	CreatedBy  primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
 	CreatedOn  int64              `json:"created_on,omitempty" bson:"created_on,omitempty"`
 	UpdatedOn  int64              `json:"updated_on,omitempty" bson:"updated_on,omitempty"`
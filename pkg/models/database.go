@@ -10,16 +10,25 @@ import (
 )
 
 var (
-	DB                  *mongo.Database
-	Client              *mongo.Client
-	UsersCollection     *mongo.Collection
-	MediaCollection     *mongo.Collection
-	BooksCollection     *mongo.Collection
-	CoursesCollection   *mongo.Collection
-	StocksCollection    *mongo.Collection
-	OrdersCollection    *mongo.Collection
-	FeedsCollection     *mongo.Collection
-	CartItemsCollection *mongo.Collection
+	DB                        *mongo.Database
+	Client                    *mongo.Client
+	UsersCollection           *mongo.Collection
+	MediaCollection           *mongo.Collection
+	BooksCollection           *mongo.Collection
+	CoursesCollection         *mongo.Collection
+	StocksCollection          *mongo.Collection
+	OrdersCollection          *mongo.Collection
+	FeedsCollection           *mongo.Collection
+	CartItemsCollection       *mongo.Collection
+	SuggestionsCollection     *mongo.Collection
+	SearchLogsCollection      *mongo.Collection
+	RecommendationsCollection *mongo.Collection
+	ReviewsCollection         *mongo.Collection
+	FeedVersionsCollection    *mongo.Collection
+	ParalinksCollection       *mongo.Collection
+	ExperimentsCollection     *mongo.Collection
+	ExposuresCollection       *mongo.Collection
+	UploadsCollection         *mongo.Collection
 )
 
 func Connect(uri string, dbname string, logger hclog.Logger) error {
@@ -45,6 +54,15 @@ func Connect(uri string, dbname string, logger hclog.Logger) error {
 	StocksCollection = DB.Collection("stocks")
 	OrdersCollection = DB.Collection("orders")
 	CartItemsCollection = DB.Collection("cartitems")
+	SuggestionsCollection = DB.Collection("suggestions")
+	SearchLogsCollection = DB.Collection("searchlogs")
+	RecommendationsCollection = DB.Collection("recommendations")
+	ReviewsCollection = DB.Collection("reviews")
+	FeedVersionsCollection = DB.Collection("feed_versions")
+	ParalinksCollection = DB.Collection("paralinks")
+	ExperimentsCollection = DB.Collection("experiments")
+	ExposuresCollection = DB.Collection("exposures")
+	UploadsCollection = DB.Collection("uploads")
 
 	log.Println("Connected to MongoDB!")
 	return nil
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ExperimentStatusDraft   = "draft"
+	ExperimentStatusRunning = "running"
+	ExperimentStatusStopped = "stopped"
+)
+
+// Experiment splits the traffic of a feed group between variants. Feeds of the
+// group that are in no variant are shown to everyone.
+type Experiment struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Name      string             `validate:"required,min=2,max=50" json:"name,omitempty" bson:"name,omitempty"`
+	Paralink  string             `validate:"required" json:"paralink,omitempty" bson:"paralink,omitempty"`
+	Status    string             `validate:"omitempty,oneof=draft running stopped" json:"status,omitempty" bson:"status,omitempty"`
+	Variants  []Variant          `validate:"required,min=2,dive" json:"variants,omitempty" bson:"variants,omitempty"`
+	CreatedBy primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	CreatedOn int64              `json:"created_on,omitempty" bson:"created_on,omitempty"`
+	UpdatedOn int64              `json:"updated_on,omitempty" bson:"updated_on,omitempty"`
+}
+
+type Variant struct {
+	Name   string               `validate:"required,min=1,max=50" json:"name,omitempty" bson:"name,omitempty"`
+	Weight int                  `validate:"required,min=1" json:"weight,omitempty" bson:"weight,omitempty"`
+	Feeds  []primitive.ObjectID `validate:"required,min=1" json:"feeds,omitempty" bson:"feeds,omitempty"`
+}
+
+// UpdateExperiment is the body of an experiment update, variants can only be
+// changed before the experiment runs
+type UpdateExperiment struct {
+	Name      string    `validate:"omitempty,min=2,max=50" json:"name,omitempty" bson:"name,omitempty"`
+	Status    string    `validate:"omitempty,oneof=draft running stopped" json:"status,omitempty" bson:"status,omitempty"`
+	Variants  []Variant `validate:"omitempty,min=2,dive" json:"variants,omitempty" bson:"variants,omitempty"`
+	UpdatedOn int64     `json:"updated_on,omitempty" bson:"updated_on,omitempty"`
+}
+
+// Exposure records that a user or an anonymous client was shown a variant
+type Exposure struct {
+	ID           primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	ExperimentID primitive.ObjectID `json:"experiment_id,omitempty" bson:"experiment_id,omitempty"`
+	Variant      string             `json:"variant,omitempty" bson:"variant,omitempty"`
+	Subject      string             `json:"subject,omitempty" bson:"subject,omitempty"` // user id or client id
+	UserID       primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	Count        int                `json:"count,omitempty" bson:"count,omitempty"`
+	FirstSeenOn  int64              `json:"first_seen_on,omitempty" bson:"first_seen_on,omitempty"`
+	LastSeenOn   int64              `json:"last_seen_on,omitempty" bson:"last_seen_on,omitempty"`
+}
+
+func NewExperiment(experiment *Experiment) *Experiment {
+	if experiment.CreatedOn == 0 {
+		experiment.CreatedOn = time.Now().UnixMilli()
+	}
+	if experiment.UpdatedOn == 0 {
+		experiment.UpdatedOn = time.Now().UnixMilli()
+	}
+	if experiment.Status == "" {
+		experiment.Status = ExperimentStatusDraft
+	}
+	return experiment
+}
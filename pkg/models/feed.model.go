@@ -6,28 +6,48 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	FeedStatusDraft     = "draft"
+	FeedStatusPublished = "published"
+	FeedStatusArchived  = "archived"
+)
+
 type Feed struct {
-	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
-	Name      string             `validate:"required,min=2,max=50" json:"name,omitempty" bson:"name,omitempty"`
-	Title     string             `json:"title,omitempty" bson:"title,omitempty"`
-	Type      string             `json:"type,omitempty" bson:"type,omitempty"`           // sections,courses,books
-	ViewType  string             `json:"view_type,omitempty" bson:"view_type,omitempty"` // banner, 2dvstack, vstack,hstack
-	Linked    string             `json:"linked,omitempty" bson:"linked,omitempty"`
-	Paralinks []string           `validate:"required" json:"paralinks,omitempty" bson:"paralinks,omitempty"`
-	Sections  []Section          `json:"sections,omitempty" bson:"sections,omitempty"`
-	Order     int                `validate:"required,number" json:"order,omitempty" bson:"order,omitempty"`
-	CreatedBy primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
-	CreatedOn int64              `json:"created_on,omitempty" bson:"created_on,omitempty"`
-	UpdatedOn int64              `json:"updated_on,omitempty" bson:"updated_on,omitempty"`
+	ID          primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Name        string             `validate:"required,min=2,max=50" json:"name,omitempty" bson:"name,omitempty"`
+	Title       string             `json:"title,omitempty" bson:"title,omitempty"`
+	Type        string             `json:"type,omitempty" bson:"type,omitempty"`           // sections,courses,books
+	ViewType    string             `json:"view_type,omitempty" bson:"view_type,omitempty"` // banner, 2dvstack, vstack,hstack
+	Linked      string             `json:"linked,omitempty" bson:"linked,omitempty"`
+	Paralinks   []string           `validate:"required" json:"paralinks,omitempty" bson:"paralinks,omitempty"`
+	Sections    []Section          `json:"sections,omitempty" bson:"sections,omitempty"`
+	Order       int                `validate:"required,number" json:"order,omitempty" bson:"order,omitempty"`
+	Status      string             `validate:"omitempty,oneof=draft published archived" json:"status,omitempty" bson:"status,omitempty"` // older feeds have none and are published
+	PublishAt   int64              `json:"publish_at,omitempty" bson:"publish_at,omitempty"`                                             // unix millis
+	UnpublishAt int64              `json:"unpublish_at,omitempty" bson:"unpublish_at,omitempty"`                                         // unix millis
+	CreatedBy   primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	CreatedOn   int64              `json:"created_on,omitempty" bson:"created_on,omitempty"`
+	UpdatedOn   int64              `json:"updated_on,omitempty" bson:"updated_on,omitempty"`
 }
 type Section struct {
 	Title    string             `json:"title,omitempty" bson:"title,omitempty"`
-	Type     string             `json:"type,omitempty" bson:"type,omitempty"` // paralink,course,book,options
+	Type     string             `json:"type,omitempty" bson:"type,omitempty"` // paralink,course,book,options,query
 	Paralink string             `json:"paralink,omitempty" bson:"paralink,omitempty"`
 	Image    primitive.ObjectID `json:"image,omitempty" bson:"image,omitempty"`
 	Course   primitive.ObjectID `json:"course,omitempty" bson:"course,omitempty"`
 	Book     primitive.ObjectID `json:"book,omitempty" bson:"book,omitempty"`
 	Options  Options            `json:"options,omitempty" bson:"options,omitempty"`
+	Query    *SectionQuery      `json:"query,omitempty" bson:"query,omitempty"`
+}
+
+// SectionQuery is a saved query that fills a section with books when the feed
+// is rendered
+type SectionQuery struct {
+	Kind   string             `json:"kind,omitempty" bson:"kind,omitempty"` // new_arrivals,most_discounted,best_sellers,course,tag
+	Course primitive.ObjectID `json:"course,omitempty" bson:"course,omitempty"`
+	Tag    string             `json:"tag,omitempty" bson:"tag,omitempty"`
+	Days   int                `json:"days,omitempty" bson:"days,omitempty"` // window of new_arrivals and best_sellers, 0 is all time
+	Limit  int                `json:"limit,omitempty" bson:"limit,omitempty"`
 }
 
 type Options struct {
@@ -42,5 +62,19 @@ func NewFeed(feed *Feed) *Feed {
 	if feed.UpdatedOn == 0 {
 		feed.UpdatedOn = time.Now().UnixMilli()
 	}
+	if feed.Status == "" {
+		feed.Status = FeedStatusPublished
+	}
 	return feed
 }
+
+// IsLive reports whether the feed is published and inside its publish window at now
+func (feed *Feed) IsLive(now int64) bool {
+	if feed.Status != "" && feed.Status != FeedStatusPublished {
+		return false
+	}
+	if feed.PublishAt != 0 && feed.PublishAt > now {
+		return false
+	}
+	return feed.UnpublishAt == 0 || feed.UnpublishAt > now
+}
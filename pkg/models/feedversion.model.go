@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FeedVersion is a snapshot of a feed. A snapshot is stored every time a feed
+// is created or updated, drafts are versions that have not been applied yet.
+type FeedVersion struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	FeedID    primitive.ObjectID `json:"feed_id,omitempty" bson:"feed_id,omitempty"`
+	Version   int                `json:"version,omitempty" bson:"version,omitempty"`
+	Draft     bool               `json:"draft" bson:"draft"`
+	Feed      Feed               `json:"feed" bson:"feed"`
+	CreatedBy primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	CreatedOn int64              `json:"created_on,omitempty" bson:"created_on,omitempty"`
+}
+
+func NewFeedVersion(feedVersion *FeedVersion) *FeedVersion {
+	if feedVersion.CreatedOn == 0 {
+		feedVersion.CreatedOn = time.Now().UnixMilli()
+	}
+	return feedVersion
+}
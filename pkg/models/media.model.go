@@ -7,14 +7,71 @@ import (
 )
 
 type Media struct {
-	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
-	Path      string             `json:"path,omitempty" bson:"path,omitempty"`
-	CreatedBy primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
-	CreatedOn int64              `json:"created_on,omitempty" bson:"created_on,omitempty"`
-	UpdatedOn int64              `json:"updated_on,omitempty" bson:"updated_on,omitempty"`
+	ID         primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Path       string             `json:"path,omitempty" bson:"path,omitempty"`
+	Variants   []MediaVariant     `json:"variants,omitempty" bson:"variants,omitempty"`
+	Hash       string             `json:"hash,omitempty" bson:"hash,omitempty"` // SHA-256 of the original
+	MimeType   string             `json:"mime_type,omitempty" bson:"mime_type,omitempty"`
+	Size       int64              `json:"size,omitempty" bson:"size,omitempty"` // in bytes
+	Width      int                `json:"width,omitempty" bson:"width,omitempty"`
+	Height     int                `json:"height,omitempty" bson:"height,omitempty"`
+	Alt        string             `validate:"max=250" json:"alt,omitempty" bson:"alt,omitempty"`
+	FocalPoint *FocalPoint        `validate:"omitempty" json:"focal_point,omitempty" bson:"focal_point,omitempty"`
+	RefCount   int                `json:"ref_count,omitempty" bson:"ref_count,omitempty"` // uploads sharing this media
+	Private    bool               `json:"private,omitempty" bson:"private,omitempty"`     // served only through signed urls
+	CreatedBy  primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	CreatedOn  int64              `json:"created_on,omitempty" bson:"created_on,omitempty"`
+	UpdatedOn  int64              `json:"updated_on,omitempty" bson:"updated_on,omitempty"`
+}
+
+// FocalPoint is the part of the image to keep when it is cropped, as
+// fractions of the width and height from the top left corner
+type FocalPoint struct {
+	X float64 `validate:"min=0,max=1" json:"x" bson:"x"`
+	Y float64 `validate:"min=0,max=1" json:"y" bson:"y"`
+}
+
+// UpdateMedia is the body of a media update, only the editorial fields can change
+type UpdateMedia struct {
+	Alt        string      `validate:"max=250" json:"alt,omitempty" bson:"alt,omitempty"`
+	FocalPoint *FocalPoint `validate:"omitempty" json:"focal_point,omitempty" bson:"focal_point,omitempty"`
+	UpdatedOn  int64       `json:"updated_on,omitempty" bson:"updated_on,omitempty"`
+}
+
+// SignMedia is the body of a signed url request of a private media
+type SignMedia struct {
+	ExpiresIn int  `validate:"omitempty,min=1,max=1440" json:"expires_in,omitempty"` // in minutes
+	BindUser  bool `json:"bind_user,omitempty"`                                      // only the requesting user can open the url
+}
+
+type SignedMedia struct {
+	Url       string `json:"url"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// MediaVariant is a resized copy of the original image
+type MediaVariant struct {
+	Size   string `json:"size,omitempty" bson:"size,omitempty"`     // thumb,card,full
+	Format string `json:"format,omitempty" bson:"format,omitempty"` // webp,jpeg
+	Path   string `json:"path,omitempty" bson:"path,omitempty"`
+	Width  int    `json:"width,omitempty" bson:"width,omitempty"`
+	Height int    `json:"height,omitempty" bson:"height,omitempty"`
+}
+
+// Variant returns the variant of size in format, or nil when the media has none
+func (media *Media) Variant(size string, format string) *MediaVariant {
+	for i := range media.Variants {
+		if media.Variants[i].Size == size && media.Variants[i].Format == format {
+			return &media.Variants[i]
+		}
+	}
+	return nil
 }
 
 func NewMedia(media *Media) *Media {
+	if media.RefCount == 0 {
+		media.RefCount = 1
+	}
 	if media.CreatedOn == 0 {
 		media.CreatedOn = time.Now().UnixMilli()
 	}
@@ -11,6 +11,9 @@ type BookList struct {
 	StockID primitive.ObjectID `validate:"required" json:"stock_id,omitempty" bson:"stock_id,omitempty"`
 }
 
+// PaymentStatusPaid is the PaymentStatus of an order whose payment went through
+const PaymentStatusPaid = "paid"
+
 type Order struct {
 	ID              primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
 	Type            string             `validate:"required" json:"type,omitempty" bson:"type,omitempty"`
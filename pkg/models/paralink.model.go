@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Paralink maps the slug used by feeds and sections to what the app should open
+type Paralink struct {
+	ID         primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Slug       string             `validate:"required,min=1,max=100" json:"slug,omitempty" bson:"slug,omitempty"`
+	TargetType string             `validate:"required,oneof=feed course book search url" json:"target_type,omitempty" bson:"target_type,omitempty"`
+	Target     string             `validate:"required" json:"target,omitempty" bson:"target,omitempty"` // feed paralink, course or book id, search query or url
+	Title      string             `json:"title,omitempty" bson:"title,omitempty"`
+	CreatedBy  primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	CreatedOn  int64              `json:"created_on,omitempty" bson:"created_on,omitempty"`
+	UpdatedOn  int64              `json:"updated_on,omitempty" bson:"updated_on,omitempty"`
+}
+
+// UpdateParalink is the body of a paralink update, the slug can not change
+// because feeds refer to it
+type UpdateParalink struct {
+	TargetType string `validate:"omitempty,oneof=feed course book search url" json:"target_type,omitempty" bson:"target_type,omitempty"`
+	Target     string `json:"target,omitempty" bson:"target,omitempty"`
+	Title      string `json:"title,omitempty" bson:"title,omitempty"`
+	UpdatedOn  int64  `json:"updated_on,omitempty" bson:"updated_on,omitempty"`
+}
+
+func NewParalink(paralink *Paralink) *Paralink {
+	if paralink.CreatedOn == 0 {
+		paralink.CreatedOn = time.Now().UnixMilli()
+	}
+	if paralink.UpdatedOn == 0 {
+		paralink.UpdatedOn = time.Now().UnixMilli()
+	}
+	return paralink
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PasswordReset is a pending password reset, only the SHA-256 of the token is
+// kept and it is deleted when used or by mongo at ExpiresAt
+type PasswordReset struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	TokenHash string             `json:"-" bson:"token_hash,omitempty"`
+	ExpiresAt time.Time          `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	CreatedOn int64              `json:"created_on,omitempty" bson:"created_on,omitempty"`
+}
+
+type ForgotPassword struct {
+	Email string `validate:"required,email" json:"email,omitempty"`
+}
+
+type ResetPassword struct {
+	Token    string `validate:"required" json:"token,omitempty"`
+	Password string `validate:"required,min=8,max=20,passwd" json:"password,omitempty"`
+}
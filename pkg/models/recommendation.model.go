@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RecommendedBook struct {
+	BookID primitive.ObjectID `json:"book_id,omitempty" bson:"book_id,omitempty"`
+	Score  int                `json:"score,omitempty" bson:"score,omitempty"`
+}
+
+// Recommendation holds the books bought or carted together with the book in ID
+type Recommendation struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Books     []RecommendedBook  `json:"books,omitempty" bson:"books,omitempty"`
+	UpdatedOn int64              `json:"updated_on,omitempty" bson:"updated_on,omitempty"`
+}
+
+func NewRecommendation(recommendation *Recommendation) *Recommendation {
+	if recommendation.UpdatedOn == 0 {
+		recommendation.UpdatedOn = time.Now().UnixMilli()
+	}
+	return recommendation
+}
@@ -0,0 +1,6 @@
+package models
+
+// Reorder sets the order of every document in IDs to its position in the list, starting at 1
+type Reorder struct {
+	IDs []string `validate:"required,min=1,max=1000" json:"ids"`
+}
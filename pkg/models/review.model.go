@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ReviewStatusPublished = "published"
+	ReviewStatusHidden    = "hidden"
+)
+
+type Review struct {
+	ID               primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	BookID           primitive.ObjectID `json:"book_id,omitempty" bson:"book_id,omitempty"`
+	UserID           primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	Rating           int                `validate:"required,min=1,max=5" json:"rating,omitempty" bson:"rating,omitempty"`
+	Text             string             `validate:"max=2000" json:"text,omitempty" bson:"text,omitempty"`
+	VerifiedPurchase bool               `json:"verified_purchase" bson:"verified_purchase"`
+	Status           string             `json:"status,omitempty" bson:"status,omitempty"` // published,hidden
+	CreatedOn        int64              `json:"created_on,omitempty" bson:"created_on,omitempty"`
+	UpdatedOn        int64              `json:"updated_on,omitempty" bson:"updated_on,omitempty"`
+}
+
+func NewReview(review *Review) *Review {
+	if review.CreatedOn == 0 {
+		review.CreatedOn = time.Now().UnixMilli()
+	}
+	if review.UpdatedOn == 0 {
+		review.UpdatedOn = time.Now().UnixMilli()
+	}
+	if review.Status == "" {
+		review.Status = ReviewStatusPublished
+	}
+	return review
+}
+
+type UpdateReview struct {
+	Rating    int    `validate:"omitempty,min=1,max=5" json:"rating,omitempty" bson:"rating,omitempty"`
+	Text      string `validate:"max=2000" json:"text,omitempty" bson:"text,omitempty"`
+	UpdatedOn int64  `json:"updated_on,omitempty" bson:"updated_on,omitempty"`
+}
+
+type ReviewStatus struct {
+	Status string `validate:"required,oneof=published hidden" json:"status,omitempty" bson:"status,omitempty"`
+}
+
+// Rating is the aggregate of the published reviews of a book
+type Rating struct {
+	Average float64 `json:"average" bson:"average"`
+	Count   int     `json:"count" bson:"count"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SearchLog struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Query     string             `json:"query,omitempty" bson:"query,omitempty"`
+	Term      string             `json:"term,omitempty" bson:"term,omitempty"`
+	Scope     string             `json:"scope,omitempty" bson:"scope,omitempty"` // books,courses,feeds,media,stocks,cartitems
+	Results   int64              `json:"results" bson:"results"`
+	UserID    primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	CreatedOn int64              `json:"created_on,omitempty" bson:"created_on,omitempty"`
+}
+
+func NewSearchLog(searchLog *SearchLog) *SearchLog {
+	if searchLog.Term == "" {
+		searchLog.Term = strings.ToLower(strings.Join(strings.Fields(searchLog.Query), " "))
+	}
+	if searchLog.CreatedOn == 0 {
+		searchLog.CreatedOn = time.Now().UnixMilli()
+	}
+	return searchLog
+}
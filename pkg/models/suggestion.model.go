@@ -0,0 +1,47 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Suggestion struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Type      string             `json:"type,omitempty" bson:"type,omitempty"` // book,course,tag
+	RefID     primitive.ObjectID `json:"ref_id,omitempty" bson:"ref_id,omitempty"`
+	Text      string             `json:"text,omitempty" bson:"text,omitempty"`
+	Keys      []string           `json:"-" bson:"keys,omitempty"`
+	Image     primitive.ObjectID `json:"image,omitempty" bson:"image,omitempty"`
+	Weight    int                `json:"weight,omitempty" bson:"weight,omitempty"`
+	UpdatedOn int64              `json:"updated_on,omitempty" bson:"updated_on,omitempty"`
+}
+
+func NewSuggestion(suggestion *Suggestion) *Suggestion {
+	suggestion.Keys = SuggestionKeys(suggestion.Text)
+	if suggestion.UpdatedOn == 0 {
+		suggestion.UpdatedOn = time.Now().UnixMilli()
+	}
+	return suggestion
+}
+
+// NormalizeSuggestion lower cases s and collapses every run of
+// non alphanumeric characters into a single space
+func NormalizeSuggestion(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+// SuggestionKeys returns the normalized text starting at every word so that a
+// prefix match on the keys finds "Engineering Mathematics" for "math" as well
+func SuggestionKeys(text string) []string {
+	words := strings.Fields(NormalizeSuggestion(text))
+	keys := []string{}
+	for i := range words {
+		keys = append(keys, strings.Join(words[i:], " "))
+	}
+	return keys
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	UploadStatusPending  = "pending"
+	UploadStatusComplete = "complete"
+	UploadStatusFailed   = "failed"
+)
+
+// Upload is a resumable upload. The bytes received so far are kept as chunks
+// in the blob store until the upload is complete and becomes a media.
+type Upload struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Length    int64              `json:"length" bson:"length"`
+	Offset    int64              `json:"offset" bson:"offset"`
+	Filename  string             `json:"filename,omitempty" bson:"filename,omitempty"`
+	Metadata  map[string]string  `json:"metadata,omitempty" bson:"metadata,omitempty"`
+	Chunks    []UploadChunk      `json:"-" bson:"chunks,omitempty"`
+	Status    string             `json:"status,omitempty" bson:"status,omitempty"` // pending,complete,failed
+	Error     string             `json:"error,omitempty" bson:"error,omitempty"`
+	MediaID   primitive.ObjectID `json:"media_id,omitempty" bson:"media_id,omitempty"`
+	CreatedBy primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	CreatedOn int64              `json:"created_on,omitempty" bson:"created_on,omitempty"`
+	UpdatedOn int64              `json:"updated_on,omitempty" bson:"updated_on,omitempty"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+}
+
+type UploadChunk struct {
+	Offset int64  `bson:"offset"`
+	Size   int64  `bson:"size"`
+	Key    string `bson:"key"`
+}
+
+func NewUpload(upload *Upload) *Upload {
+	if upload.Status == "" {
+		upload.Status = UploadStatusPending
+	}
+	if upload.CreatedOn == 0 {
+		upload.CreatedOn = time.Now().UnixMilli()
+	}
+	if upload.UpdatedOn == 0 {
+		upload.UpdatedOn = time.Now().UnixMilli()
+	}
+	return upload
+}
@@ -47,14 +47,14 @@ func NewUser(user *User) {
 }
 
 type UpdateUser struct {
-	Name         string   `validate:"min_len=3,max_len=40" json:"name,omitempty" bson:"name,omitempty"`
-	Phone        string   `validate:"min_len=10,max_len=10,regexp=^[0-9]*$" json:"phone,omitempty" bson:"phone,omitempty"`
-	Email        string   `json:"email,omitempty" bson:"email,omitempty"`
-	Location     []string `validate:"location" json:"location,omitempty" bson:"location,omitempty"`
-	Address      string   `json:"address,omitempty" bson:"address,omitempty"`
-	Verified     Verified `json:"verified,omitempty" bson:"verified,omitempty"`
-	RefreshToken string   `json:"refresh_token,omitempty" bson:"refresh_token,omitempty"`
-	UpdatedOn    int      `json:"updated_on,omitempty" bson:"updated_on,omitempty"`
+	Name         string    `validate:"min_len=3,max_len=40" json:"name,omitempty" bson:"name,omitempty"`
+	Phone        string    `validate:"min_len=10,max_len=10,regexp=^[0-9]*$" json:"phone,omitempty" bson:"phone,omitempty"`
+	Email        string    `json:"email,omitempty" bson:"email,omitempty"`
+	Location     []string  `validate:"location" json:"location,omitempty" bson:"location,omitempty"`
+	Address      string    `json:"address,omitempty" bson:"address,omitempty"`
+	Verified     *Verified `json:"verified,omitempty" bson:"verified,omitempty"` // a pointer so other updates leave it as is
+	RefreshToken string    `json:"refresh_token,omitempty" bson:"refresh_token,omitempty"`
+	UpdatedOn    int       `json:"updated_on,omitempty" bson:"updated_on,omitempty"`
 }
 
 type ChangePassword struct {
@@ -3,6 +3,8 @@ package models
 import (
 	"fmt"
 	"log"
+	"reflect"
+	"strings"
 	"unicode"
 
 	"github.com/go-playground/validator/v10"
@@ -22,15 +24,17 @@ func (v ValidationError) Error() string {
 	case "required":
 		err = fmt.Sprintf("%s is required", v.Field())
 	case "min":
-		err = fmt.Sprintf("%s should be atleast %s charactars", v.Field(), v.Param())
+		err = fmt.Sprintf("%s should be atleast %s%s", v.Field(), v.Param(), unit(v.Kind()))
 	case "max":
-		err = fmt.Sprintf("%s should be atmost %s charactars", v.Field(), v.Param())
+		err = fmt.Sprintf("%s should be atmost %s%s", v.Field(), v.Param(), unit(v.Kind()))
 	case "email":
 		err = fmt.Sprintf("Enter a valid %s", v.Field())
 	case "numeric":
 		err = fmt.Sprintf("%s should only have numeric", v.Field())
 	case "passwd":
 		err = fmt.Sprintf("%s should have Minimum eight characters, at least one uppercase letter, one lowercase letter, one number and one special character", v.Field())
+	case "oneof":
+		err = fmt.Sprintf("%s should be one of %s", v.Field(), strings.Join(strings.Fields(v.Param()), ", "))
 	}
 
 	// fmt.Sprintf(
@@ -42,6 +46,19 @@ func (v ValidationError) Error() string {
 	return err
 }
 
+// unit is the unit of min and max for the kind of field, numbers have none
+func unit(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return ""
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return " items"
+	}
+	return " charactars"
+}
+
 // ValidationErrors is a wrapper for list of ValidationError
 type ValidationErrors []ValidationError
 
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Verification is the pending email or phone verification of a user, only the
+// bcrypt hash of the code is kept and mongo drops it at ExpiresAt
+type Verification struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	Email     string             `json:"email,omitempty" bson:"email,omitempty"`
+	Phone     string             `json:"phone,omitempty" bson:"phone,omitempty"`
+	CodeHash  string             `json:"-" bson:"code_hash,omitempty"`
+	Attempts  int                `json:"attempts" bson:"attempts"` // wrong codes tried
+	Sends     int                `json:"sends" bson:"sends"`       // codes sent until it expires
+	SentOn    int64              `json:"sent_on,omitempty" bson:"sent_on,omitempty"`
+	ExpiresAt time.Time          `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	CreatedOn int64              `json:"created_on,omitempty" bson:"created_on,omitempty"`
+	UpdatedOn int64              `json:"updated_on,omitempty" bson:"updated_on,omitempty"`
+}
+
+type VerifyEmail struct {
+	Email string `validate:"required,email" json:"email,omitempty"`
+	Code  string `validate:"required,numeric,len=6" json:"code,omitempty"`
+}
+
+type ResendVerification struct {
+	Email string `validate:"required,email" json:"email,omitempty"`
+}
+
+type VerifyPhone struct {
+	Code string `validate:"required,numeric,len=6" json:"code,omitempty"`
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/SairamVemula/booksland-backend-go/pkg/controllers"
+	"github.com/SairamVemula/booksland-backend-go/pkg/middlewares"
 	"github.com/SairamVemula/booksland-backend-go/pkg/models"
 	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
 	"github.com/gorilla/mux"
@@ -12,6 +13,7 @@ import (
 
 var RegisterAuthToutes = func(router *mux.Router, logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) {
 	c := controllers.NewAuthController(logger, configs, validator)
+	m := middlewares.NewMiddleware(logger, configs, validator)
 
 	sr := router.PathPrefix("/auth").Subrouter()
 
@@ -21,6 +23,8 @@ var RegisterAuthToutes = func(router *mux.Router, logger hclog.Logger, configs *
 	sr.HandleFunc("/reset-verification", c.ResentVerification).Methods(http.MethodPost)
 	sr.HandleFunc("/reset-password", c.ResetPassword).Methods(http.MethodPost)
 	sr.HandleFunc("/verify", c.Verify).Methods(http.MethodPost)
+	sr.Handle("/phone/otp", m.AuthWithRoles([]string{"admin", "user"}, middlewares.AuthenticatedHandler(c.SendPhoneOtp))).Methods(http.MethodPost)
+	sr.Handle("/phone/verify", m.AuthWithRoles([]string{"admin", "user"}, middlewares.AuthenticatedHandler(c.VerifyPhone))).Methods(http.MethodPost)
 	sr.HandleFunc("/refresh", c.Refresh).Methods(http.MethodGet)
 	sr.HandleFunc("/logout", c.Logout).Methods(http.MethodGet)
 }
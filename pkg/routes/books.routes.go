@@ -13,13 +13,16 @@ import (
 
 var RegisterBooksRoutes = func(router *mux.Router, logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) {
 	c := controllers.NewBookController(logger, configs, validator)
+	rc := controllers.NewRecommendationController(logger, configs, validator)
 	m := middlewares.NewMiddleware(logger, configs, validator)
 
 	sr := router.PathPrefix("/books").Subrouter()
 
 	sr.Handle("", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Create))).Methods(http.MethodPost)
 	sr.Handle("", m.AuthWithRoles([]string{}, middlewares.AuthenticatedHandler(c.Get))).Methods(http.MethodGet)
+	sr.Handle("/order", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Reorder))).Methods(http.MethodPut)
 	sr.Handle("/{book_id}", m.AuthWithRoles([]string{}, middlewares.AuthenticatedHandler(c.GetById))).Methods(http.MethodGet)
 	sr.Handle("/{book_id}", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Update))).Methods(http.MethodPatch)
 	sr.Handle("/{book_id}", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Delete))).Methods(http.MethodDelete)
+	sr.Handle("/{book_id}/recommendations", m.AuthWithRoles([]string{}, middlewares.AuthenticatedHandler(rc.GetByBook))).Methods(http.MethodGet)
 }
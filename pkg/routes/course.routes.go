@@ -21,6 +21,7 @@ var RegisterCourseRoutes = func(router *mux.Router, logger hclog.Logger, configs
 
 	sr.Handle("", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.CreateCourse))).Methods(http.MethodPost)
 	sr.Handle("", m.AuthWithRoles([]string{}, middlewares.AuthenticatedHandler(c.GetCourse))).Methods(http.MethodGet)
+	sr.Handle("/order", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.ReorderCourses))).Methods(http.MethodPut)
 	sr.Handle("/{course_id}", m.AuthWithRoles([]string{}, middlewares.AuthenticatedHandler(c.GetCourseById))).Methods(http.MethodGet)
 	sr.Handle("/{course_id}", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.UpdateCourse))).Methods(http.MethodPatch)
 	sr.Handle("/{course_id}", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.DeleteCourse))).Methods(http.MethodDelete)
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/controllers"
+	"github.com/SairamVemula/booksland-backend-go/pkg/middlewares"
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+var RegisterExperimentsRoutes = func(router *mux.Router, logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) {
+	c := controllers.NewExperimentController(logger, configs, validator)
+	m := middlewares.NewMiddleware(logger, configs, validator)
+
+	sr := router.PathPrefix("/experiments").Subrouter()
+
+	sr.Handle("", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Create))).Methods(http.MethodPost)
+	sr.Handle("", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Get))).Methods(http.MethodGet)
+	sr.Handle("/{experiment_id}", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.GetById))).Methods(http.MethodGet)
+	sr.Handle("/{experiment_id}", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Update))).Methods(http.MethodPatch)
+	sr.Handle("/{experiment_id}", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Delete))).Methods(http.MethodDelete)
+	sr.Handle("/{experiment_id}/report", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Report))).Methods(http.MethodGet)
+}
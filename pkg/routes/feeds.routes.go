@@ -13,13 +13,21 @@ import (
 
 var RegisterFeedsRoutes = func(router *mux.Router, logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) {
 	c := controllers.NewFeedController(logger, configs, validator)
+	vc := controllers.NewFeedVersionController(logger, configs, validator)
 	m := middlewares.NewMiddleware(logger, configs, validator)
 
 	sr := router.PathPrefix("/feeds").Subrouter()
 
 	sr.Handle("", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Create))).Methods(http.MethodPost)
 	sr.Handle("", m.AuthWithRoles([]string{}, middlewares.AuthenticatedHandler(c.Get))).Methods(http.MethodGet)
+	sr.Handle("/order", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Reorder))).Methods(http.MethodPut)
+	sr.Handle("/cache/stats", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.CacheStats))).Methods(http.MethodGet)
 	sr.Handle("/{feed_id}", m.AuthWithRoles([]string{}, middlewares.AuthenticatedHandler(c.GetById))).Methods(http.MethodGet)
 	sr.Handle("/{feed_id}", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Update))).Methods(http.MethodPatch)
 	sr.Handle("/{feed_id}", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Delete))).Methods(http.MethodDelete)
+
+	sr.Handle("/{feed_id}/versions", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(vc.Get))).Methods(http.MethodGet)
+	sr.Handle("/{feed_id}/versions", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(vc.Create))).Methods(http.MethodPost)
+	sr.Handle("/{feed_id}/versions/{version}/preview", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(vc.Preview))).Methods(http.MethodGet)
+	sr.Handle("/{feed_id}/versions/{version}/rollback", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(vc.Rollback))).Methods(http.MethodPost)
 }
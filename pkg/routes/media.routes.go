@@ -13,16 +13,30 @@ import (
 
 var RegisterMediaRoutes = func(router *mux.Router, logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) {
 	c := controllers.NewMediaController(logger, configs, validator)
+	uc := controllers.NewUploadController(logger, configs, validator)
 	m := middlewares.NewMiddleware(logger, configs, validator)
 
 	sr := router.PathPrefix("/media").Subrouter()
 
+	// registered before /{media_id} so orphans is not read as an id
+	sr.Handle("/orphans", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Orphans))).Methods(http.MethodGet)
+	sr.Handle("/gc", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Collect))).Methods(http.MethodPost)
+
+	// tus resumable uploads
+	sr.HandleFunc("/uploads", uc.Options).Methods(http.MethodOptions)
+	sr.Handle("/uploads", m.AuthWithRoles([]string{"admin", "user"}, middlewares.AuthenticatedHandler(uc.Create))).Methods(http.MethodPost)
+	sr.Handle("/uploads/{upload_id}", m.AuthWithRoles([]string{"admin", "user"}, middlewares.AuthenticatedHandler(uc.Head))).Methods(http.MethodHead)
+	sr.Handle("/uploads/{upload_id}", m.AuthWithRoles([]string{"admin", "user"}, middlewares.AuthenticatedHandler(uc.Patch))).Methods(http.MethodPatch)
+	sr.Handle("/uploads/{upload_id}", m.AuthWithRoles([]string{"admin", "user"}, middlewares.AuthenticatedHandler(uc.Delete))).Methods(http.MethodDelete)
+
 	// sr.HandleFunc("", c.Create).Methods(http.MethodPost)
-	sr.HandleFunc("", c.Get).Methods(http.MethodGet)
-	sr.HandleFunc("/{media_id}", c.GetById).Methods(http.MethodGet)
-	sr.HandleFunc("/{media_id}", c.Delete).Methods(http.MethodDelete)
+	sr.Handle("", m.AuthWithRoles([]string{}, middlewares.AuthenticatedHandler(c.Get))).Methods(http.MethodGet)
+	sr.Handle("/{media_id}", m.AuthWithRoles([]string{}, middlewares.AuthenticatedHandler(c.GetById))).Methods(http.MethodGet)
+	sr.Handle("/{media_id}", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Delete))).Methods(http.MethodDelete)
 
-	sr.Handle("", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Create))).Methods(http.MethodPost)
+	sr.Handle("", m.AuthWithRoles([]string{"admin", "user"}, middlewares.AuthenticatedHandler(c.Create))).Methods(http.MethodPost)
+	sr.Handle("/{media_id}", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Update))).Methods(http.MethodPatch)
+	sr.Handle("/{media_id}/sign", m.AuthWithRoles([]string{"admin", "user"}, middlewares.AuthenticatedHandler(c.Sign))).Methods(http.MethodPost)
 	// sr.Handle("", m.AuthWithRoles([]string{}, middlewares.AuthenticatedHandler(c.Delete))).Methods(http.MethodGet)
 	// sr.Handle("/{book_id}", m.AuthWithRoles([]string{}, middlewares.AuthenticatedHandler(c.GetById))).Methods(http.MethodGet)
 	// sr.Handle("/{book_id}", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Update))).Methods(http.MethodPatch)
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/controllers"
+	"github.com/SairamVemula/booksland-backend-go/pkg/middlewares"
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+var RegisterParalinksRoutes = func(router *mux.Router, logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) {
+	c := controllers.NewParalinkController(logger, configs, validator)
+	m := middlewares.NewMiddleware(logger, configs, validator)
+
+	sr := router.PathPrefix("/links").Subrouter()
+
+	sr.Handle("", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Create))).Methods(http.MethodPost)
+	sr.Handle("", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Get))).Methods(http.MethodGet)
+	sr.Handle("/{paralink}", m.AuthWithRoles([]string{}, middlewares.AuthenticatedHandler(c.Resolve))).Methods(http.MethodGet)
+	sr.Handle("/{paralink}", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Update))).Methods(http.MethodPatch)
+	sr.Handle("/{paralink}", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Delete))).Methods(http.MethodDelete)
+}
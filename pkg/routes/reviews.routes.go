@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/controllers"
+	"github.com/SairamVemula/booksland-backend-go/pkg/middlewares"
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+var RegisterReviewsRoutes = func(router *mux.Router, logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) {
+	c := controllers.NewReviewController(logger, configs, validator)
+	m := middlewares.NewMiddleware(logger, configs, validator)
+
+	router.Handle("/books/{book_id}/reviews", m.AuthWithRoles([]string{"admin", "user"}, middlewares.AuthenticatedHandler(c.Create))).Methods(http.MethodPost)
+	router.Handle("/books/{book_id}/reviews", m.AuthWithRoles([]string{}, middlewares.AuthenticatedHandler(c.Get))).Methods(http.MethodGet)
+
+	sr := router.PathPrefix("/reviews").Subrouter()
+
+	sr.Handle("/{review_id}", m.AuthWithRoles([]string{"admin", "user"}, middlewares.AuthenticatedHandler(c.Update))).Methods(http.MethodPatch)
+	sr.Handle("/{review_id}", m.AuthWithRoles([]string{"admin", "user"}, middlewares.AuthenticatedHandler(c.Delete))).Methods(http.MethodDelete)
+	sr.Handle("/{review_id}/status", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.SetStatus))).Methods(http.MethodPatch)
+}
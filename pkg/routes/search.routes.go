@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/controllers"
+	"github.com/SairamVemula/booksland-backend-go/pkg/middlewares"
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-hclog"
+)
+
+var RegisterSearchRoutes = func(router *mux.Router, logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) {
+	c := controllers.NewSearchController(logger, configs, validator)
+	m := middlewares.NewMiddleware(logger, configs, validator)
+
+	sr := router.PathPrefix("/search").Subrouter()
+
+	sr.Handle("/suggest", m.AuthWithRoles([]string{}, middlewares.AuthenticatedHandler(c.Suggest))).Methods(http.MethodGet)
+	sr.Handle("/suggest/rebuild", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Rebuild))).Methods(http.MethodPost)
+	sr.Handle("/analytics/top", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.TopQueries))).Methods(http.MethodGet)
+	sr.Handle("/analytics/zero-results", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.ZeroResultQueries))).Methods(http.MethodGet)
+	sr.Handle("/analytics/trends", m.AuthWithRoles([]string{"admin"}, middlewares.AuthenticatedHandler(c.Trends))).Methods(http.MethodGet)
+}
@@ -17,6 +17,7 @@ var as *AuthService
 
 type AuthService struct {
 	us        *UserService
+	ms        *Mail
 	logger    hclog.Logger
 	configs   *utils.Configurations
 	validator *models.Validation
@@ -27,11 +28,43 @@ func NewAuthService(logger hclog.Logger, configs *utils.Configurations, validato
 		return as
 	}
 	us := NewUserService(logger, configs, validator)
-	return &AuthService{us, logger, configs, validator}
+	return &AuthService{us, NewMailService(logger, configs, validator), logger, configs, validator}
 }
 
 func (as *AuthService) Register(ctx context.Context, user *models.User) (*models.User, *utils.RestError) {
-	return as.us.Create(ctx, user)
+	user, RestError := as.us.Create(ctx, user)
+	if RestError != nil {
+		return nil, RestError
+	}
+	// the account works without it, the code can be sent again
+	if RestError := as.ms.SendVerification(ctx, user); RestError != nil {
+		as.logger.Error("unable to send verification code", "user", user.ID.Hex(), "error", RestError.Message)
+	}
+	return user, nil
+}
+
+func (as *AuthService) ResendVerification(ctx context.Context, email string) {
+	as.ms.ResendVerification(ctx, email)
+}
+
+func (as *AuthService) ForgotPassword(email string) {
+	as.ms.ForgotPassword(email)
+}
+
+func (as *AuthService) ResetPassword(ctx context.Context, token string, password string) *utils.RestError {
+	return as.ms.ResetPassword(ctx, token, password)
+}
+
+func (as *AuthService) SendPhoneOtp(ctx context.Context, user *models.User) *utils.RestError {
+	return as.ms.SendPhoneOtp(ctx, user)
+}
+
+func (as *AuthService) VerifyPhone(ctx context.Context, user *models.User, code string) *utils.RestError {
+	return as.ms.VerifyPhone(ctx, user, code)
+}
+
+func (as *AuthService) VerifyEmail(ctx context.Context, email string, code string) *utils.RestError {
+	return as.ms.VerifyEmail(ctx, email, code)
 }
 
 func (as *AuthService) Login(ctx context.Context, loginUser *models.LoginUser) (*models.User, *utils.RestError) {
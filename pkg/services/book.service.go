@@ -20,27 +20,33 @@ type BookService struct {
 	logger    hclog.Logger
 	configs   *utils.Configurations
 	validator *models.Validation
+	search    *SearchService
+	searchLog *SearchLogService
+	cache     utils.Cache
 }
 
 func NewBookService(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *BookService {
-	return &BookService{models.BooksCollection, logger, configs, validator}
+	return &BookService{models.BooksCollection, logger, configs, validator, NewSearchService(logger, configs, validator), NewSearchLogService(logger, configs, validator), NewFeedCache(logger, configs)}
 }
 
 func (bs *BookService) Create(ctx context.Context, book *models.Book) (*models.Book, *utils.RestError) {
 	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
 	defer cancel()
 	book = models.NewBook(book)
+	// rating is maintained by the reviews
+	book.Rating = nil
 	result, err := bs.bc.InsertOne(ctx, book)
 	if err != nil {
 		RestError := utils.InternalErr("can't insert user to the database.")
 		return nil, RestError
 	}
 	book.ID = result.InsertedID.(primitive.ObjectID)
+	bs.search.Index(ctx, "book", book.ID, book.Name, book.Tags, book.Image)
+	bs.cache.Invalidate(ctx, BOOKS_TAG)
 	return book, nil
 }
 
 func (bs *BookService) Find(ctx context.Context, params *GetQuery) (bson.M, *utils.RestError) {
-	skip := (params.Page - 1) * params.Limit
 	query := bson.M{}
 	// opts := options.Find().SetSkip(skip).SetLimit(params.Limit)
 	if params.ID != "" {
@@ -62,7 +68,6 @@ func (bs *BookService) Find(ctx context.Context, params *GetQuery) (bson.M, *uti
 		}
 	}
 
-	matchStage := bson.D{{"$match", query}}
 	imagePipelineStage := bson.D{
 		{"$lookup", bson.D{
 			{"from", "media"},
@@ -86,7 +91,7 @@ func (bs *BookService) Find(ctx context.Context, params *GetQuery) (bson.M, *uti
 		},
 	}
 	imageUnwindStage := bson.D{{"$unwind", bson.D{{"path", "$image"}, {"preserveNullAndEmptyArrays", true}}}}
-	setStage := bson.D{{Key: "$addFields", Value: bson.M{"image.url": bson.D{{"$concat", bson.A{bs.configs.AssetsUrl, "$image.path"}}}}}}
+	setStage := bson.D{{Key: "$addFields", Value: mediaUrlFields(bs.configs.AssetsUrl, "image.")}}
 	stockLookup := bson.D{{
 		"$lookup", bson.D{
 			{"from", "stocks"},
@@ -141,19 +146,11 @@ func (bs *BookService) Find(ctx context.Context, params *GetQuery) (bson.M, *uti
 	}
 	courseUnwindStage := bson.D{{"$unwind", bson.D{{"path", "$course"}, {"preserveNullAndEmptyArrays", true}}}}
 
-	sortStage := bson.D{{Key: "$sort", Value: bson.D{{"order", 1}}}}
-	skipStage := bson.D{{Key: "$skip", Value: skip}}
-	limitStage := bson.D{{Key: "$limit", Value: params.Limit}}
-	countStage := bson.D{{Key: "$count", Value: "count"}}
-
-	facetStage := bson.D{{
-		"$facet", bson.D{
-			{"docs", bson.A{sortStage, skipStage, limitStage}},
-			{"total", bson.A{countStage}},
-		},
-	}}
-	unwindStage2 := bson.D{{"$unwind", "$total"}}
-	pipeline := mongo.Pipeline{matchStage, imagePipelineStage, imageUnwindStage, setStage, stockLookup, coursePipelineStage, courseUnwindStage, facetStage, unwindStage2}
+	sortBy := SortBy{"order", 1}
+	pipeline, after, RestError := Paginate(params, query, mongo.Pipeline{imagePipelineStage, imageUnwindStage, setStage, stockLookup, coursePipelineStage, courseUnwindStage}, sortBy)
+	if RestError != nil {
+		return nil, RestError
+	}
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
 	defer cancel()
@@ -171,10 +168,9 @@ func (bs *BookService) Find(ctx context.Context, params *GetQuery) (bson.M, *uti
 		RestError := utils.InternalErr(err.Error())
 		return nil, RestError
 	}
-	if len(books) == 0 {
-		return bson.M{"docs": []bson.M{}, "total": bson.M{"count": 0}}, nil
-	}
-	return books[0], nil
+	res := PageResult(params, books, sortBy, after)
+	bs.searchLog.Log(params, "books", res)
+	return res, nil
 }
 func (bs *BookService) FindById(ctx context.Context, book_id string) (*primitive.M, *utils.RestError) {
 	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
@@ -207,7 +203,7 @@ func (bs *BookService) FindById(ctx context.Context, book_id string) (*primitive
 		},
 	}}
 	mediaUnWind := bson.D{{"$unwind", bson.D{{"path", "$image"}, {"preserveNullAndEmptyArrays", true}}}}
-	mediaConcat := bson.D{{Key: "$addFields", Value: bson.M{"image.url": bson.D{{"$concat", bson.A{bs.configs.AssetsUrl, "$image.path"}}}}}}
+	mediaConcat := bson.D{{Key: "$addFields", Value: mediaUrlFields(bs.configs.AssetsUrl, "image.")}}
 	stockLookup := bson.D{{
 		"$lookup", bson.D{
 			{"from", "stocks"},
@@ -267,7 +263,7 @@ func (bs *BookService) DeleteById(ctx context.Context, book_id string) *utils.Re
 		RestError := utils.NotFound("Invalid user_id")
 		return RestError
 	}
-	result, err := cs.cc.DeleteOne(ctx, bson.M{"_id": id})
+	result, err := bs.bc.DeleteOne(ctx, bson.M{"_id": id})
 	if err != nil {
 		RestError := utils.NotFound("faild to delete.")
 		return RestError
@@ -276,6 +272,8 @@ func (bs *BookService) DeleteById(ctx context.Context, book_id string) *utils.Re
 		RestError := utils.NotFound("book not found.")
 		return RestError
 	}
+	bs.search.Remove(ctx, "book", id)
+	bs.cache.Invalidate(ctx, BOOKS_TAG, BookTag(id))
 	return nil
 }
 
@@ -291,6 +289,8 @@ func (bs *BookService) UpdateById(ctx context.Context, book_id string, updateBoo
 	if RestError != nil {
 		return nil, RestError
 	}
+	// rating is maintained by the reviews
+	updateBook.Rating = nil
 	after := options.After
 	opts := options.FindOneAndUpdateOptions{ReturnDocument: &after}
 	result := bs.bc.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": updateBook}, &opts)
@@ -302,5 +302,24 @@ func (bs *BookService) UpdateById(ctx context.Context, book_id string, updateBoo
 	if decodeErr != nil {
 		return nil, utils.InternalErr(decodeErr.Error())
 	}
+	var updated models.Book
+	if err := result.Decode(&updated); err == nil {
+		bs.search.Index(ctx, "book", updated.ID, updated.Name, updated.Tags, updated.Image)
+	}
+	bs.cache.Invalidate(ctx, BOOKS_TAG, BookTag(id))
 	return book, nil
 }
+
+// Reorder sets the order of the books to their position in ids
+func (bs *BookService) Reorder(ctx context.Context, ids *models.Reorder) (bson.M, *utils.RestError) {
+	reordered, res, RestError := reorder(ctx, bs.bc, ids)
+	if RestError != nil {
+		return nil, RestError
+	}
+	tags := []string{BOOKS_TAG}
+	for _, id := range reordered {
+		tags = append(tags, BookTag(id))
+	}
+	bs.cache.Invalidate(ctx, tags...)
+	return res, nil
+}
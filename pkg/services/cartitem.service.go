@@ -20,10 +20,11 @@ type CartItemService struct {
 	logger    hclog.Logger
 	configs   *utils.Configurations
 	validator *models.Validation
+	searchLog *SearchLogService
 }
 
 func NewCartItemService(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *CartItemService {
-	return &CartItemService{models.CartItemsCollection, logger, configs, validator}
+	return &CartItemService{models.CartItemsCollection, logger, configs, validator, NewSearchLogService(logger, configs, validator)}
 }
 
 func (cis *CartItemService) Create(ctx context.Context, cartItem *models.CartItem) (*models.CartItem, *utils.RestError) {
@@ -40,7 +41,6 @@ func (cis *CartItemService) Create(ctx context.Context, cartItem *models.CartIte
 }
 
 func (cis *CartItemService) Find(ctx context.Context, params *GetQuery) (bson.M, *utils.RestError) {
-	skip := (params.Page - 1) * params.Limit
 	query := bson.M{}
 	// opts := options.Find().SetSkip(skip).SetLimit(params.Limit)
 	if params.ID != "" {
@@ -62,7 +62,6 @@ func (cis *CartItemService) Find(ctx context.Context, params *GetQuery) (bson.M,
 		}
 	}
 
-	matchStage := bson.D{{"$match", query}}
 	stockLookup := bson.D{{
 		"$lookup", bson.D{
 			{"from", "stocks"},
@@ -142,19 +141,11 @@ func (cis *CartItemService) Find(ctx context.Context, params *GetQuery) (bson.M,
 	}
 	courseUnwindStage := bson.D{{"$unwind", bson.D{{"path", "$course"}, {"preserveNullAndEmptyArrays", true}}}}
 
-	sortStage := bson.D{{Key: "$sort", Value: bson.D{{"_id", 1}}}}
-	skipStage := bson.D{{Key: "$skip", Value: skip}}
-	limitStage := bson.D{{Key: "$limit", Value: params.Limit}}
-	countStage := bson.D{{Key: "$count", Value: "count"}}
-
-	facetStage := bson.D{{
-		"$facet", bson.D{
-			{"docs", bson.A{sortStage, skipStage, limitStage}},
-			{"total", bson.A{countStage}},
-		},
-	}}
-	unwindStage2 := bson.D{{"$unwind", "$total"}}
-	pipeline := mongo.Pipeline{matchStage, bookPipelineStage, bookUnwindStage, coursePipelineStage, courseUnwindStage, facetStage, unwindStage2}
+	sortBy := SortBy{"_id", 1}
+	pipeline, after, RestError := Paginate(params, query, mongo.Pipeline{bookPipelineStage, bookUnwindStage, coursePipelineStage, courseUnwindStage}, sortBy)
+	if RestError != nil {
+		return nil, RestError
+	}
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
 	defer cancel()
@@ -172,10 +163,9 @@ func (cis *CartItemService) Find(ctx context.Context, params *GetQuery) (bson.M,
 		RestError := utils.InternalErr(err.Error())
 		return nil, RestError
 	}
-	if len(cartItems) == 0 {
-		return bson.M{"docs": []bson.M{}, "total": bson.M{"count": 0}}, nil
-	}
-	return cartItems[0], nil
+	res := PageResult(params, cartItems, sortBy, after)
+	cis.searchLog.Log(params, "cartitems", res)
+	return res, nil
 }
 func (cis *CartItemService) FindById(ctx context.Context, cartItem_id string) (*models.CartItem, *utils.RestError) {
 	var cartItem models.CartItem
@@ -20,10 +20,13 @@ type CourseService struct {
 	logger    hclog.Logger
 	configs   *utils.Configurations
 	validator *models.Validation
+	search    *SearchService
+	searchLog *SearchLogService
+	cache     utils.Cache
 }
 
 func NewCourseService(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *CourseService {
-	return &CourseService{models.CoursesCollection, logger, configs, validator}
+	return &CourseService{models.CoursesCollection, logger, configs, validator, NewSearchService(logger, configs, validator), NewSearchLogService(logger, configs, validator), NewFeedCache(logger, configs)}
 }
 
 func (cs *CourseService) Create(ctx context.Context, course *models.Course) (*models.Course, *utils.RestError) {
@@ -36,6 +39,7 @@ func (cs *CourseService) Create(ctx context.Context, course *models.Course) (*mo
 		return nil, RestError
 	}
 	course.ID = result.InsertedID.(primitive.ObjectID)
+	cs.search.Index(ctx, "course", course.ID, course.Name, course.Tags, course.Image)
 	return course, nil
 }
 
@@ -48,18 +52,28 @@ type GetQuery struct {
 	CourseID string `schema:"course_id"`
 	BookID   string `schema:"book_id"`
 	Paralink string `schema:"paralink"`
+	Cursor   string `schema:"cursor"`
+	Status   string `schema:"status"`
+	ClientID string `schema:"client_id"`
+
+	UserID        primitive.ObjectID   `schema:"-"`
+	ExcludeIDs    []primitive.ObjectID `schema:"-"`
+	Variant       string               `schema:"-"` // experiment variants the response was built for
+	SkipSearchLog bool                 `schema:"-"` // searches the server runs itself stay out of the analytics
 }
 
 func NewGetQuery(q *GetQuery) {
-	if q.Limit == 0 {
+	if q.Limit <= 0 {
 		q.Limit = 20
 	}
-	if q.Page == 0 {
+	if q.Limit > MAX_LIMIT {
+		q.Limit = MAX_LIMIT
+	}
+	if q.Page <= 0 {
 		q.Page = 1
 	}
 }
 func (cs *CourseService) Find(ctx context.Context, params *GetQuery) (bson.M, *utils.RestError) {
-	skip := (params.Page - 1) * params.Limit
 	query := bson.M{}
 	if params.ID != "" {
 		_id, err := primitive.ObjectIDFromHex(params.ID)
@@ -88,7 +102,6 @@ func (cs *CourseService) Find(ctx context.Context, params *GetQuery) (bson.M, *u
 		}
 	}
 
-	matchStage := bson.D{{"$match", query}}
 	imagePipelineStage := bson.D{
 		{"$lookup", bson.D{
 			{"from", "media"},
@@ -112,21 +125,12 @@ func (cs *CourseService) Find(ctx context.Context, params *GetQuery) (bson.M, *u
 		},
 	}
 	imageUnwindStage := bson.D{{"$unwind", bson.D{{"path", "$image"}, {"preserveNullAndEmptyArrays", true}}}}
-	setStage := bson.D{{Key: "$addFields", Value: bson.M{"image.url": bson.D{{"$concat", bson.A{cs.configs.AssetsUrl, "$image.path"}}}}}}
-	sortStage := bson.D{{Key: "$sort", Value: bson.D{{"order", 1}}}}
-	skipStage := bson.D{{Key: "$skip", Value: skip}}
-	limitStage := bson.D{{Key: "$limit", Value: params.Limit}}
-	countStage := bson.D{{Key: "$count", Value: "count"}}
-
-	facetStage := bson.D{{
-		"$facet", bson.D{
-			{"docs", bson.A{sortStage, skipStage, limitStage}},
-			{"total", bson.A{countStage}},
-		},
-	}}
-	unwindStage := bson.D{{"$unwind", "$total"}}
-
-	pipeline := mongo.Pipeline{matchStage, imagePipelineStage, imageUnwindStage, setStage, facetStage, unwindStage}
+	setStage := bson.D{{Key: "$addFields", Value: mediaUrlFields(cs.configs.AssetsUrl, "image.")}}
+	sortBy := SortBy{"order", 1}
+	pipeline, after, RestError := Paginate(params, query, mongo.Pipeline{imagePipelineStage, imageUnwindStage, setStage}, sortBy)
+	if RestError != nil {
+		return nil, RestError
+	}
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
 	defer cancel()
@@ -143,10 +147,9 @@ func (cs *CourseService) Find(ctx context.Context, params *GetQuery) (bson.M, *u
 		RestError := utils.InternalErr("Internal Server Error")
 		return nil, RestError
 	}
-	if len(courses) == 0 {
-		return bson.M{"docs": []bson.M{}, "total": bson.M{"count": 0}}, nil
-	}
-	return courses[0], nil
+	res := PageResult(params, courses, sortBy, after)
+	cs.searchLog.Log(params, "courses", res)
+	return res, nil
 }
 func (cs *CourseService) FindById(ctx context.Context, course_id string) (*models.Course, *utils.RestError) {
 	var course models.Course
@@ -182,6 +185,8 @@ func (cs *CourseService) DeleteById(ctx context.Context, course_id string) *util
 		RestError := utils.NotFound("course not found.")
 		return RestError
 	}
+	cs.search.Remove(ctx, "course", id)
+	cs.cache.Invalidate(ctx, CourseTag(id))
 	return nil
 }
 
@@ -208,5 +213,21 @@ func (cs *CourseService) UpdateById(ctx context.Context, course_id string, updat
 	if decodeErr != nil {
 		return nil, utils.InternalErr(decodeErr.Error())
 	}
+	cs.search.Index(ctx, "course", course.ID, course.Name, course.Tags, course.Image)
+	cs.cache.Invalidate(ctx, CourseTag(id))
 	return course, nil
 }
+
+// Reorder sets the order of the courses to their position in ids
+func (cs *CourseService) Reorder(ctx context.Context, ids *models.Reorder) (bson.M, *utils.RestError) {
+	reordered, res, RestError := reorder(ctx, cs.cc, ids)
+	if RestError != nil {
+		return nil, RestError
+	}
+	tags := []string{}
+	for _, id := range reordered {
+		tags = append(tags, CourseTag(id))
+	}
+	cs.cache.Invalidate(ctx, tags...)
+	return res, nil
+}
@@ -0,0 +1,418 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/hashicorp/go-hclog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const exposureBatchSize = 100
+
+var exs *ExperimentService
+
+// ExperimentService runs A/B experiments on feed groups. Exposures are queued
+// and written in batches like the search logs so GET /feeds never waits on them.
+type ExperimentService struct {
+	exc       *mongo.Collection
+	epc       *mongo.Collection
+	queue     chan *models.Exposure
+	logger    hclog.Logger
+	configs   *utils.Configurations
+	validator *models.Validation
+	cache     utils.Cache
+}
+
+func NewExperimentService(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *ExperimentService {
+	if exs != nil {
+		return exs
+	}
+	exs = &ExperimentService{models.ExperimentsCollection, models.ExposuresCollection, make(chan *models.Exposure, 10*exposureBatchSize), logger, configs, validator, NewFeedCache(logger, configs)}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	_, err := exs.exc.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{"paralink", 1}, {"status", 1}}})
+	if err != nil {
+		logger.Error("unable to create experiment indexes", "error", err)
+	}
+	_, err = exs.epc.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{"experiment_id", 1}, {"subject", 1}}, Options: options.Index().SetUnique(true)})
+	if err != nil {
+		logger.Error("unable to create exposure indexes", "error", err)
+	}
+	go exs.run()
+	return exs
+}
+
+// checkVariants makes sure the variants have distinct names and that their
+// feeds exist in the feed group of the experiment, each in one variant only
+func (exs *ExperimentService) checkVariants(ctx context.Context, paralink string, variants []models.Variant) *utils.RestError {
+	names := map[string]bool{}
+	feeds := map[primitive.ObjectID]string{}
+	ids := []primitive.ObjectID{}
+	for _, variant := range variants {
+		if names[variant.Name] {
+			return utils.BadRequest(fmt.Sprintf("variant %s is repeated", variant.Name))
+		}
+		names[variant.Name] = true
+		for _, id := range variant.Feeds {
+			if other, ok := feeds[id]; ok {
+				return utils.BadRequest(fmt.Sprintf("feed %s is in variants %s and %s", id.Hex(), other, variant.Name))
+			}
+			feeds[id] = variant.Name
+			ids = append(ids, id)
+		}
+	}
+	count, err := models.FeedsCollection.CountDocuments(ctx, bson.M{"_id": bson.M{"$in": ids}, "paralinks": paralink})
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+	if int(count) != len(ids) {
+		return utils.BadRequest(fmt.Sprintf("every feed of the variants should exist and have the paralink %s", paralink))
+	}
+	return nil
+}
+
+// checkRunning allows one running experiment per feed group
+func (exs *ExperimentService) checkRunning(ctx context.Context, id primitive.ObjectID, paralink string) *utils.RestError {
+	count, err := exs.exc.CountDocuments(ctx, bson.M{"_id": bson.M{"$ne": id}, "paralink": paralink, "status": models.ExperimentStatusRunning})
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+	if count > 0 {
+		return utils.BadRequest(fmt.Sprintf("an experiment is already running on %s", paralink))
+	}
+	return nil
+}
+
+func (exs *ExperimentService) Create(ctx context.Context, experiment *models.Experiment) (*models.Experiment, *utils.RestError) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+	experiment = models.NewExperiment(experiment)
+	if RestError := exs.checkVariants(ctx, experiment.Paralink, experiment.Variants); RestError != nil {
+		return nil, RestError
+	}
+	if experiment.Status == models.ExperimentStatusRunning {
+		if RestError := exs.checkRunning(ctx, primitive.NilObjectID, experiment.Paralink); RestError != nil {
+			return nil, RestError
+		}
+	}
+	result, err := exs.exc.InsertOne(ctx, experiment)
+	if err != nil {
+		return nil, utils.InternalErr("can't insert experiment to the database.")
+	}
+	experiment.ID = result.InsertedID.(primitive.ObjectID)
+	exs.cache.Invalidate(ctx, FEEDS_TAG)
+	return experiment, nil
+}
+
+func (exs *ExperimentService) Find(ctx context.Context, params *GetQuery) (bson.M, *utils.RestError) {
+	query := bson.M{}
+	if params.Paralink != "" {
+		query["paralink"] = params.Paralink
+	}
+	if params.Status != "" {
+		query["status"] = params.Status
+	}
+	sortBy := SortBy{"_id", -1}
+	pipeline, after, RestError := Paginate(params, query, mongo.Pipeline{}, sortBy)
+	if RestError != nil {
+		return nil, RestError
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	cursor, err := exs.exc.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	var experiments []bson.M
+	if err = cursor.All(ctx, &experiments); err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	return PageResult(params, experiments, sortBy, after), nil
+}
+
+func (exs *ExperimentService) FindById(ctx context.Context, experiment_id string) (*models.Experiment, *utils.RestError) {
+	var experiment models.Experiment
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	id, e := primitive.ObjectIDFromHex(experiment_id)
+	if e != nil {
+		return nil, utils.NotFound("Invalid experiment_id")
+	}
+	err := exs.exc.FindOne(ctx, bson.M{"_id": id}).Decode(&experiment)
+	if err != nil {
+		return nil, utils.NotFound("experiment not found.")
+	}
+	return &experiment, nil
+}
+
+func (exs *ExperimentService) UpdateById(ctx context.Context, experiment_id string, updateExperiment *models.UpdateExperiment) (*models.Experiment, *utils.RestError) {
+	experiment, RestError := exs.FindById(ctx, experiment_id)
+	if RestError != nil {
+		return nil, RestError
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+	if updateExperiment.Variants != nil {
+		if experiment.Status != models.ExperimentStatusDraft {
+			return nil, utils.BadRequest("variants can only be changed while the experiment is a draft")
+		}
+		if RestError := exs.checkVariants(ctx, experiment.Paralink, updateExperiment.Variants); RestError != nil {
+			return nil, RestError
+		}
+	}
+	if updateExperiment.Status == models.ExperimentStatusRunning {
+		if RestError := exs.checkRunning(ctx, experiment.ID, experiment.Paralink); RestError != nil {
+			return nil, RestError
+		}
+	}
+	updateExperiment.UpdatedOn = time.Now().UnixMilli()
+	after := options.After
+	opts := options.FindOneAndUpdateOptions{ReturnDocument: &after}
+	result := exs.exc.FindOneAndUpdate(ctx, bson.M{"_id": experiment.ID}, bson.M{"$set": updateExperiment}, &opts)
+	if result.Err() != nil {
+		return nil, utils.InternalErr(result.Err().Error())
+	}
+	if err := result.Decode(experiment); err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	exs.cache.Invalidate(ctx, FEEDS_TAG)
+	return experiment, nil
+}
+
+// DeleteById deletes an experiment with its exposures
+func (exs *ExperimentService) DeleteById(ctx context.Context, experiment_id string) *utils.RestError {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+	id, e := primitive.ObjectIDFromHex(experiment_id)
+	if e != nil {
+		return utils.NotFound("Invalid experiment_id")
+	}
+	result, err := exs.exc.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return utils.InternalErr("faild to delete.")
+	}
+	if result.DeletedCount == 0 {
+		return utils.NotFound("experiment not found.")
+	}
+	if _, err := exs.epc.DeleteMany(ctx, bson.M{"experiment_id": id}); err != nil {
+		exs.logger.Error("unable to delete exposures", "experiment", experiment_id, "error", err)
+	}
+	exs.cache.Invalidate(ctx, FEEDS_TAG)
+	return nil
+}
+
+// pick returns the variant of subject. The hash of the experiment and subject
+// is stable, so a subject keeps its variant for the life of the experiment.
+func pick(experiment *models.Experiment, subject string) models.Variant {
+	total := 0
+	for _, variant := range experiment.Variants {
+		total += variant.Weight
+	}
+	if subject == "" || total <= 0 {
+		return experiment.Variants[0]
+	}
+	h := fnv.New32a()
+	h.Write([]byte(experiment.ID.Hex() + ":" + subject))
+	bucket := int(h.Sum32() % uint32(total))
+	for _, variant := range experiment.Variants {
+		if bucket < variant.Weight {
+			return variant
+		}
+		bucket -= variant.Weight
+	}
+	return experiment.Variants[0]
+}
+
+// Assign hides the feeds of the variants params is not assigned to and queues
+// the exposure. subject is the user id or the client id of anonymous users,
+// without one the first variant is shown and nothing is recorded.
+func (exs *ExperimentService) Assign(ctx context.Context, params *GetQuery, subject string) *utils.RestError {
+	if params.Paralink == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+	cursor, err := exs.exc.Find(ctx, bson.M{"paralink": params.Paralink, "status": models.ExperimentStatusRunning})
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+	var experiments []models.Experiment
+	if err = cursor.All(ctx, &experiments); err != nil {
+		return utils.InternalErr(err.Error())
+	}
+
+	assigned := []string{}
+	now := time.Now().UnixMilli()
+	for i := range experiments {
+		experiment := &experiments[i]
+		variant := pick(experiment, subject)
+		for _, other := range experiment.Variants {
+			if other.Name != variant.Name {
+				params.ExcludeIDs = append(params.ExcludeIDs, other.Feeds...)
+			}
+		}
+		assigned = append(assigned, experiment.ID.Hex()+":"+variant.Name)
+		if subject == "" {
+			continue
+		}
+		exposure := &models.Exposure{ExperimentID: experiment.ID, Variant: variant.Name, Subject: subject, UserID: params.UserID, FirstSeenOn: now, LastSeenOn: now}
+		select {
+		case exs.queue <- exposure:
+		default:
+			exs.logger.Warn("exposure queue is full, dropping", "experiment", experiment.ID.Hex())
+		}
+	}
+	params.Variant = strings.Join(assigned, ",")
+	return nil
+}
+
+func (exs *ExperimentService) run() {
+	ticker := time.NewTicker(time.Second * 5)
+	defer ticker.Stop()
+	batch := []mongo.WriteModel{}
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		defer cancel()
+		if _, err := exs.epc.BulkWrite(ctx, batch, options.BulkWrite().SetOrdered(false)); err != nil {
+			exs.logger.Error("unable to write exposures", "error", err)
+		}
+		batch = []mongo.WriteModel{}
+	}
+	for {
+		select {
+		case exposure := <-exs.queue:
+			onInsert := bson.M{"variant": exposure.Variant, "first_seen_on": exposure.FirstSeenOn}
+			if !exposure.UserID.IsZero() {
+				onInsert["user_id"] = exposure.UserID
+			}
+			update := bson.M{
+				"$setOnInsert": onInsert,
+				"$set":         bson.M{"last_seen_on": exposure.LastSeenOn},
+				"$inc":         bson.M{"count": 1},
+			}
+			filter := bson.M{"experiment_id": exposure.ExperimentID, "subject": exposure.Subject}
+			batch = append(batch, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+			if len(batch) >= exposureBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// conversionLookup counts the documents of collection a user created after
+// their first exposure
+func conversionLookup(collection string, userField string, as string) bson.D {
+	return bson.D{{
+		"$lookup", bson.D{
+			{"from", collection},
+			{"let", bson.M{"user_id": "$user_id", "since": "$first_seen_on"}},
+			{"pipeline", bson.A{
+				bson.D{{
+					"$match", bson.D{{
+						"$expr",
+						bson.D{{
+							"$and",
+							bson.A{
+								bson.D{{"$eq", bson.A{"$" + userField, "$$user_id"}}},
+								bson.D{{"$gte", bson.A{"$created_on", "$$since"}}},
+							},
+						}},
+					}},
+				}},
+				bson.D{{"$count", "count"}},
+			},
+			},
+			{"as", as},
+		},
+	}}
+}
+
+// rate divides field by the identified users of the variant
+func rate(field string) bson.D {
+	return bson.D{{"$cond", bson.A{
+		bson.D{{"$gt", bson.A{"$users", 0}}},
+		bson.D{{"$divide", bson.A{"$" + field, "$users"}}},
+		0,
+	}}}
+}
+
+// Report returns the exposures and conversions of every variant. Only signed
+// in users can add to cart or order, so conversion rates are per user exposed.
+func (exs *ExperimentService) Report(ctx context.Context, experiment_id string) (bson.M, *utils.RestError) {
+	experiment, RestError := exs.FindById(ctx, experiment_id)
+	if RestError != nil {
+		return nil, RestError
+	}
+	matchStage := bson.D{{"$match", bson.M{"experiment_id": experiment.ID}}}
+	converted := func(as string) bson.D {
+		return bson.D{{"$sum", bson.D{{"$cond", bson.A{bson.D{{"$gt", bson.A{bson.D{{"$size", "$" + as}}, 0}}}, 1, 0}}}}}
+	}
+	total := func(as string) bson.D {
+		return bson.D{{"$sum", bson.D{{"$sum", "$" + as + ".count"}}}}
+	}
+	groupStage := bson.D{{
+		"$group", bson.D{
+			{"_id", "$variant"},
+			{"exposures", bson.D{{"$sum", "$count"}}},
+			{"subjects", bson.D{{"$sum", 1}}},
+			{"users", bson.D{{"$sum", bson.D{{"$cond", bson.A{bson.D{{"$gt", bson.A{"$user_id", nil}}}, 1, 0}}}}}},
+			{"cart_conversions", converted("cart")},
+			{"cart_adds", total("cart")},
+			{"order_conversions", converted("orders")},
+			{"orders", total("orders")},
+		},
+	}}
+	projectStage := bson.D{{"$project", bson.D{
+		{"_id", 0},
+		{"variant", "$_id"},
+		{"exposures", 1},
+		{"subjects", 1},
+		{"users", 1},
+		{"cart_conversions", 1},
+		{"cart_adds", 1},
+		{"cart_conversion_rate", rate("cart_conversions")},
+		{"order_conversions", 1},
+		{"orders", 1},
+		{"order_conversion_rate", rate("order_conversions")},
+	}}}
+	sortStage := bson.D{{"$sort", bson.D{{"variant", 1}}}}
+	pipeline := mongo.Pipeline{
+		matchStage,
+		conversionLookup("cartitems", "user_id", "cart"),
+		conversionLookup("orders", "created_by", "orders"),
+		groupStage,
+		projectStage,
+		sortStage,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+	cursor, err := exs.epc.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	variants := []bson.M{}
+	if err = cursor.All(ctx, &variants); err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	return bson.M{"experiment": experiment, "variants": variants}, nil
+}
@@ -13,6 +13,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// FEED_STATUS_LIVE filters the published feeds that are inside their publish window
+const FEED_STATUS_LIVE = "live"
+
 var fs *FeedService
 
 type FeedService struct {
@@ -20,52 +23,49 @@ type FeedService struct {
 	logger    hclog.Logger
 	configs   *utils.Configurations
 	validator *models.Validation
+	searchLog *SearchLogService
+	versions  *FeedVersionService
+	cache     utils.Cache
 }
 
 func NewFeedService(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *FeedService {
-	return &FeedService{models.FeedsCollection, logger, configs, validator}
+	return &FeedService{models.FeedsCollection, logger, configs, validator, NewSearchLogService(logger, configs, validator), NewFeedVersionService(logger, configs, validator), NewFeedCache(logger, configs)}
 }
 
 func (fs *FeedService) Create(ctx context.Context, feed *models.Feed) (*models.Feed, *utils.RestError) {
 	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
 	defer cancel()
 	feed = models.NewFeed(feed)
+	if RestError := checkPublishWindow(feed.PublishAt, feed.UnpublishAt); RestError != nil {
+		return nil, RestError
+	}
+	if RestError := fs.validateFeed(ctx, feed); RestError != nil {
+		return nil, RestError
+	}
 	result, err := fs.bc.InsertOne(ctx, feed)
 	if err != nil {
 		RestError := utils.InternalErr("can't insert user to the database.")
 		return nil, RestError
 	}
 	feed.ID = result.InsertedID.(primitive.ObjectID)
+	fs.cache.Invalidate(ctx, FEEDS_TAG)
+	if _, RestError := fs.versions.Snapshot(ctx, feed, feed.CreatedBy, false); RestError != nil {
+		fs.logger.Error("unable to store feed version", "feed", feed.ID.Hex(), "error", RestError.Message)
+	}
 	return feed, nil
 }
 
-func (fs *FeedService) Find(ctx context.Context, params *GetQuery) (bson.M, *utils.RestError) {
-	skip := (params.Page - 1) * params.Limit
-	query := bson.M{}
-	// opts := options.Find().SetSkip(skip).SetLimit(params.Limit)
-	if params.ID != "" {
-		_id, err := primitive.ObjectIDFromHex(params.ID)
-		if err != nil {
-			return nil, utils.InternalErr(err.Error())
-		}
-		query["_id"] = _id
-	}
-	if params.Paralink != "" {
-		query["paralinks"] = bson.M{"$elemMatch": bson.M{"$eq": params.Paralink}}
-	}
-
-	if params.Search != "" {
-		query["$or"] = bson.A{
-			bson.M{
-				"name": bson.M{"$regex": params.Search, "$options": "i"},
-			},
-			bson.M{
-				"tags": bson.M{"$elemMatch": bson.M{"$regex": params.Search, "$options": "i"}},
-			},
-		}
+// checkPublishWindow makes sure a feed is unpublished after it is published
+func checkPublishWindow(publishAt int64, unpublishAt int64) *utils.RestError {
+	if publishAt != 0 && unpublishAt != 0 && unpublishAt <= publishAt {
+		return utils.BadRequest("unpublish_at should be after publish_at")
 	}
+	return nil
+}
 
-	matchStage := bson.D{{"$match", query}}
+// renderStages joins the images, courses and books of the feed sections. They
+// are shared by Find and the preview of feed versions.
+func (fs *FeedService) renderStages() mongo.Pipeline {
 	sectionsUnWindStage := bson.D{{"$unwind", bson.D{{"path", "$sections"}, {"preserveNullAndEmptyArrays", true}}}}
 
 	coursePipelineStage := bson.D{
@@ -106,7 +106,7 @@ func (fs *FeedService) Find(ctx context.Context, params *GetQuery) (bson.M, *uti
 					},
 				}},
 				bson.D{{"$unwind", bson.D{{"path", "$image"}, {"preserveNullAndEmptyArrays", true}}}},
-				bson.D{{Key: "$addFields", Value: bson.M{"image.url": bson.D{{"$concat", bson.A{fs.configs.AssetsUrl, "$image.path"}}}}}},
+				bson.D{{Key: "$addFields", Value: mediaUrlFields(fs.configs.AssetsUrl, "image.")}},
 			},
 			},
 			{"as", "sections.course"},
@@ -153,7 +153,7 @@ func (fs *FeedService) Find(ctx context.Context, params *GetQuery) (bson.M, *uti
 					},
 				}},
 				bson.D{{"$unwind", bson.D{{"path", "$image"}, {"preserveNullAndEmptyArrays", true}}}},
-				bson.D{{Key: "$addFields", Value: bson.M{"image.url": bson.D{{"$concat", bson.A{fs.configs.AssetsUrl, "$image.path"}}}}}},
+				bson.D{{Key: "$addFields", Value: mediaUrlFields(fs.configs.AssetsUrl, "image.")}},
 				bson.D{{
 					"$lookup", bson.D{
 						{"from", "stocks"},
@@ -215,7 +215,7 @@ func (fs *FeedService) Find(ctx context.Context, params *GetQuery) (bson.M, *uti
 		},
 	}
 	imageUnwindStage := bson.D{{"$unwind", bson.D{{"path", "$sections.image"}, {"preserveNullAndEmptyArrays", true}}}}
-	setStage := bson.D{{Key: "$addFields", Value: bson.M{"sections.image.url": bson.D{{"$concat", bson.A{fs.configs.AssetsUrl, "$sections.image.path"}}}}}}
+	setStage := bson.D{{Key: "$addFields", Value: mediaUrlFields(fs.configs.AssetsUrl, "sections.image.")}}
 
 	groupStage1 := bson.D{
 		{"$group", bson.D{
@@ -230,23 +230,13 @@ func (fs *FeedService) Find(ctx context.Context, params *GetQuery) (bson.M, *uti
 			{"view_type", bson.M{"$first": "$view_type"}},
 			{"created_by", bson.M{"$first": "$created_by"}},
 			{"order", bson.M{"$first": "$order"}},
+			{"status", bson.M{"$first": "$status"}},
+			{"publish_at", bson.M{"$first": "$publish_at"}},
+			{"unpublish_at", bson.M{"$first": "$unpublish_at"}},
 		}},
 	}
 
-	sortStage := bson.D{{Key: "$sort", Value: bson.D{{"order", 1}}}}
-	skipStage := bson.D{{Key: "$skip", Value: skip}}
-	limitStage := bson.D{{Key: "$limit", Value: params.Limit}}
-	countStage := bson.D{{Key: "$count", Value: "count"}}
-
-	facetStage := bson.D{{
-		"$facet", bson.D{
-			{"docs", bson.A{sortStage, skipStage, limitStage}},
-			{"total", bson.A{countStage}},
-		},
-	}}
-	unwindStage2 := bson.D{{"$unwind", "$total"}}
-	pipeline := mongo.Pipeline{
-		matchStage,
+	return mongo.Pipeline{
 		sectionsUnWindStage,
 		imagePipelineStage,
 		imageUnwindStage,
@@ -256,8 +246,64 @@ func (fs *FeedService) Find(ctx context.Context, params *GetQuery) (bson.M, *uti
 		bookUnwindStage,
 		setStage,
 		groupStage1,
-		facetStage,
-		unwindStage2,
+	}
+}
+
+func (fs *FeedService) Find(ctx context.Context, params *GetQuery) (bson.M, *utils.RestError) {
+	query := bson.M{}
+	// opts := options.Find().SetSkip(skip).SetLimit(params.Limit)
+	if params.ID != "" {
+		_id, err := primitive.ObjectIDFromHex(params.ID)
+		if err != nil {
+			return nil, utils.InternalErr(err.Error())
+		}
+		query["_id"] = _id
+	}
+	if params.Paralink != "" {
+		query["paralinks"] = bson.M{"$elemMatch": bson.M{"$eq": params.Paralink}}
+	}
+	// feeds of the experiment variants the user is not assigned to
+	if len(params.ExcludeIDs) > 0 {
+		if id, ok := query["_id"]; ok {
+			query["_id"] = bson.M{"$eq": id, "$nin": params.ExcludeIDs}
+		} else {
+			query["_id"] = bson.M{"$nin": params.ExcludeIDs}
+		}
+	}
+	switch params.Status {
+	case "":
+	case FEED_STATUS_LIVE:
+		now := time.Now().UnixMilli()
+		query["status"] = bson.M{"$in": bson.A{models.FeedStatusPublished, nil}}
+		query["publish_at"] = bson.M{"$not": bson.M{"$gt": now}}
+		query["unpublish_at"] = bson.M{"$not": bson.M{"$lte": now}}
+	case models.FeedStatusPublished:
+		query["status"] = bson.M{"$in": bson.A{models.FeedStatusPublished, nil}}
+	default:
+		query["status"] = params.Status
+	}
+
+	if params.Search != "" {
+		query["$or"] = bson.A{
+			bson.M{
+				"name": bson.M{"$regex": params.Search, "$options": "i"},
+			},
+			bson.M{
+				"tags": bson.M{"$elemMatch": bson.M{"$regex": params.Search, "$options": "i"}},
+			},
+		}
+	}
+
+	key := feedCacheKey(params)
+	if res, ok := fs.cached(ctx, key); ok {
+		fs.searchLog.Log(params, "feeds", res)
+		return res, nil
+	}
+
+	sortBy := SortBy{"order", 1}
+	pipeline, after, RestError := Paginate(params, query, fs.renderStages(), sortBy)
+	if RestError != nil {
+		return nil, RestError
 	}
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
@@ -276,10 +322,13 @@ func (fs *FeedService) Find(ctx context.Context, params *GetQuery) (bson.M, *uti
 		RestError := utils.InternalErr(err.Error())
 		return nil, RestError
 	}
-	if len(feeds) == 0 {
-		return bson.M{"docs": []bson.M{}, "total": bson.M{"count": 0}}, nil
+	res := PageResult(params, feeds, sortBy, after)
+	if err := fs.evaluateQueries(ctx, res); err != nil {
+		return nil, utils.InternalErr(err.Error())
 	}
-	return feeds[0], nil
+	fs.cacheResult(ctx, key, params, res)
+	fs.searchLog.Log(params, "feeds", res)
+	return res, nil
 }
 func (fs *FeedService) FindById(ctx context.Context, feed_id string) (*models.Feed, *utils.RestError) {
 	var feed models.Feed
@@ -306,7 +355,7 @@ func (fs *FeedService) DeleteById(ctx context.Context, feed_id string) *utils.Re
 		RestError := utils.NotFound("Invalid user_id")
 		return RestError
 	}
-	result, err := cs.cc.DeleteOne(ctx, bson.M{"_id": id})
+	result, err := fs.bc.DeleteOne(ctx, bson.M{"_id": id})
 	if err != nil {
 		RestError := utils.NotFound("faild to delete.")
 		return RestError
@@ -315,6 +364,7 @@ func (fs *FeedService) DeleteById(ctx context.Context, feed_id string) *utils.Re
 		RestError := utils.NotFound("feed not found.")
 		return RestError
 	}
+	fs.cache.Invalidate(ctx, FEEDS_TAG)
 	return nil
 }
 
@@ -330,6 +380,32 @@ func (fs *FeedService) UpdateById(ctx context.Context, feed_id string, updateFee
 	if RestError != nil {
 		return nil, RestError
 	}
+	switch updateFeed.Status {
+	case "", models.FeedStatusDraft, models.FeedStatusPublished, models.FeedStatusArchived:
+	default:
+		return nil, utils.BadRequest("status should be one of draft, published, archived")
+	}
+	publishAt, unpublishAt := feed.PublishAt, feed.UnpublishAt
+	if updateFeed.PublishAt != 0 {
+		publishAt = updateFeed.PublishAt
+	}
+	if updateFeed.UnpublishAt != 0 {
+		unpublishAt = updateFeed.UnpublishAt
+	}
+	if RestError := checkPublishWindow(publishAt, unpublishAt); RestError != nil {
+		return nil, RestError
+	}
+	// the sections are checked against the view type the feed ends up with
+	merged := *feed
+	if updateFeed.ViewType != "" {
+		merged.ViewType = updateFeed.ViewType
+	}
+	if updateFeed.Sections != nil {
+		merged.Sections = updateFeed.Sections
+	}
+	if RestError := fs.validateFeed(ctx, &merged); RestError != nil {
+		return nil, RestError
+	}
 	after := options.After
 	opts := options.FindOneAndUpdateOptions{ReturnDocument: &after}
 	result := fs.bc.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": updateFeed}, &opts)
@@ -341,5 +417,109 @@ func (fs *FeedService) UpdateById(ctx context.Context, feed_id string, updateFee
 	if decodeErr != nil {
 		return nil, utils.InternalErr(decodeErr.Error())
 	}
+	fs.cache.Invalidate(ctx, FEEDS_TAG)
+	if _, RestError := fs.versions.Snapshot(ctx, feed, updateFeed.CreatedBy, false); RestError != nil {
+		fs.logger.Error("unable to store feed version", "feed", feed_id, "error", RestError.Message)
+	}
 	return feed, nil
 }
+
+// Reorder sets the order of the feeds to their position in ids
+func (fs *FeedService) Reorder(ctx context.Context, ids *models.Reorder) (bson.M, *utils.RestError) {
+	_, res, RestError := reorder(ctx, fs.bc, ids)
+	if RestError != nil {
+		return nil, RestError
+	}
+	fs.cache.Invalidate(ctx, FEEDS_TAG)
+	return res, nil
+}
+
+// CreateDraft stores draft as a new version of the feed without applying it
+func (fs *FeedService) CreateDraft(ctx context.Context, feed_id string, draft *models.Feed, user primitive.ObjectID) (*models.FeedVersion, *utils.RestError) {
+	feed, RestError := fs.FindById(ctx, feed_id)
+	if RestError != nil {
+		return nil, RestError
+	}
+	draft.ID = feed.ID
+	draft.CreatedOn = feed.CreatedOn
+	draft.UpdatedOn = time.Now().UnixMilli()
+	draft = models.NewFeed(draft)
+	if RestError := checkPublishWindow(draft.PublishAt, draft.UnpublishAt); RestError != nil {
+		return nil, RestError
+	}
+	if RestError := fs.validateFeed(ctx, draft); RestError != nil {
+		return nil, RestError
+	}
+	return fs.versions.Snapshot(ctx, draft, user, true)
+}
+
+// Preview renders a version of the feed through the same stages as Find
+func (fs *FeedService) Preview(ctx context.Context, feed_id string, version int) (bson.M, *utils.RestError) {
+	id, e := primitive.ObjectIDFromHex(feed_id)
+	if e != nil {
+		return nil, utils.NotFound("Invalid feed_id")
+	}
+	pipeline := mongo.Pipeline{
+		bson.D{{"$match", bson.M{"feed_id": id, "version": version}}},
+		bson.D{{"$replaceRoot", bson.M{"newRoot": "$feed"}}},
+	}
+	pipeline = append(pipeline, fs.renderStages()...)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	cursor, err := models.FeedVersionsCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	var feeds []bson.M
+	if err = cursor.All(ctx, &feeds); err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	if len(feeds) == 0 {
+		return nil, utils.NotFound("version not found.")
+	}
+	// evaluateQueries walks the docs of a page result
+	if err := fs.evaluateQueries(ctx, bson.M{"docs": feeds[:1]}); err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	return feeds[0], nil
+}
+
+// Rollback replaces the feed with a stored version and records the result as
+// the newest version, so a rollback can itself be rolled back
+func (fs *FeedService) Rollback(ctx context.Context, feed_id string, version int, user primitive.ObjectID) (*models.Feed, *utils.RestError) {
+	current, RestError := fs.FindById(ctx, feed_id)
+	if RestError != nil {
+		return nil, RestError
+	}
+	feedVersion, RestError := fs.versions.FindByVersion(ctx, feed_id, version)
+	if RestError != nil {
+		return nil, RestError
+	}
+	feed := feedVersion.Feed
+	feed.ID = current.ID
+	feed.CreatedBy = user
+	feed.CreatedOn = current.CreatedOn
+	feed.UpdatedOn = time.Now().UnixMilli()
+	if RestError := checkPublishWindow(feed.PublishAt, feed.UnpublishAt); RestError != nil {
+		return nil, RestError
+	}
+	// a version can reference books or media deleted since it was stored
+	if RestError := fs.validateFeed(ctx, &feed); RestError != nil {
+		return nil, RestError
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	_, err := fs.bc.ReplaceOne(ctx, bson.M{"_id": feed.ID}, feed)
+	if err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	fs.cache.Invalidate(ctx, FEEDS_TAG)
+	if _, RestError := fs.versions.Snapshot(ctx, &feed, user, false); RestError != nil {
+		fs.logger.Error("unable to store feed version", "feed", feed_id, "error", RestError.Message)
+	}
+	return &feed, nil
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/hashicorp/go-hclog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FEEDS_TAG is carried by every cached feed response, any change to a feed drops them all
+const FEEDS_TAG = "feeds"
+
+var feedCache utils.Cache
+
+// NewFeedCache returns the cache of rendered feed responses. It is shared with
+// the services of the documents the feeds render so they can invalidate it.
+func NewFeedCache(logger hclog.Logger, configs *utils.Configurations) utils.Cache {
+	if feedCache != nil {
+		return feedCache
+	}
+	feedCache = utils.NewCache(logger, configs)
+	return feedCache
+}
+
+func BookTag(id primitive.ObjectID) string   { return "book:" + id.Hex() }
+func CourseTag(id primitive.ObjectID) string { return "course:" + id.Hex() }
+func MediaTag(id primitive.ObjectID) string  { return "media:" + id.Hex() }
+
+// feedCacheKey builds the cache key from the query parameters that change the
+// feed response
+func feedCacheKey(params *GetQuery) string {
+	values := url.Values{}
+	values.Set("id", params.ID)
+	values.Set("page", strconv.FormatInt(params.Page, 10))
+	values.Set("limit", strconv.FormatInt(params.Limit, 10))
+	values.Set("search", params.Search)
+	values.Set("sort", params.Sort)
+	values.Set("paralink", params.Paralink)
+	values.Set("cursor", params.Cursor)
+	values.Set("status", params.Status)
+	values.Set("variant", params.Variant)
+	return "feeds:" + values.Encode()
+}
+
+// feedCacheTags names the books, courses and media rendered in res. Query
+// sections depend on every book so they carry BOOKS_TAG.
+func feedCacheTags(res bson.M) []string {
+	seen := map[string]bool{FEEDS_TAG: true}
+	tags := []string{FEEDS_TAG}
+	add := func(tag string) {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	// id reads the _id of a looked up document or a plain reference
+	id := func(value interface{}) (primitive.ObjectID, bool) {
+		switch v := value.(type) {
+		case primitive.ObjectID:
+			return v, true
+		case bson.M:
+			oid, ok := v["_id"].(primitive.ObjectID)
+			return oid, ok
+		}
+		return primitive.NilObjectID, false
+	}
+	var walk func(sections interface{})
+	walk = func(sections interface{}) {
+		list, _ := sections.(bson.A)
+		for _, item := range list {
+			section, ok := item.(bson.M)
+			if !ok {
+				continue
+			}
+			if oid, ok := id(section["image"]); ok {
+				add(MediaTag(oid))
+			}
+			if section["type"] == "query" {
+				add(BOOKS_TAG)
+			}
+			for field, tag := range map[string]func(primitive.ObjectID) string{"course": CourseTag, "book": BookTag} {
+				if oid, ok := id(section[field]); ok {
+					add(tag(oid))
+				}
+				if doc, ok := section[field].(bson.M); ok {
+					if oid, ok := id(doc["image"]); ok {
+						add(MediaTag(oid))
+					}
+				}
+			}
+			if opts, ok := section["options"].(bson.M); ok {
+				walk(opts["sections"])
+			}
+		}
+	}
+
+	docs, _ := res["docs"].(bson.A)
+	if docs == nil {
+		if list, ok := res["docs"].([]bson.M); ok {
+			for _, doc := range list {
+				docs = append(docs, doc)
+			}
+		}
+	}
+	for _, doc := range docs {
+		if feed, ok := doc.(bson.M); ok {
+			walk(feed["sections"])
+		}
+	}
+	return tags
+}
+
+// nextTransition returns when the next scheduled feed goes live or expires
+// after now, or 0 when nothing is scheduled
+func (fs *FeedService) nextTransition(ctx context.Context, now int64) int64 {
+	next := int64(0)
+	for _, field := range []string{"publish_at", "unpublish_at"} {
+		var feed models.Feed
+		opts := options.FindOne().SetSort(bson.D{{field, 1}}).SetProjection(bson.M{field: 1})
+		err := fs.bc.FindOne(ctx, bson.M{field: bson.M{"$gt": now}}, opts).Decode(&feed)
+		if err != nil {
+			continue
+		}
+		at := feed.PublishAt
+		if field == "unpublish_at" {
+			at = feed.UnpublishAt
+		}
+		if next == 0 || at < next {
+			next = at
+		}
+	}
+	return next
+}
+
+// cacheResult stores res under key. Live feeds are only cached until the next
+// scheduled feed changes what is live.
+func (fs *FeedService) cacheResult(ctx context.Context, key string, params *GetQuery, res bson.M) {
+	ttl := time.Second * time.Duration(fs.configs.FeedCacheTTL)
+	if ttl <= 0 {
+		return
+	}
+	if params.Status == FEED_STATUS_LIVE {
+		now := time.Now().UnixMilli()
+		if next := fs.nextTransition(ctx, now); next != 0 && time.Duration(next-now)*time.Millisecond < ttl {
+			ttl = time.Duration(next-now) * time.Millisecond
+		}
+	}
+	raw, err := bson.Marshal(res)
+	if err != nil {
+		fs.logger.Error("unable to encode feeds for the cache", "error", err)
+		return
+	}
+	fs.cache.Set(ctx, key, raw, ttl, feedCacheTags(res))
+}
+
+func (fs *FeedService) cached(ctx context.Context, key string) (bson.M, bool) {
+	raw, ok := fs.cache.Get(ctx, key)
+	if !ok {
+		return nil, false
+	}
+	var res bson.M
+	if err := bson.Unmarshal(raw, &res); err != nil {
+		return nil, false
+	}
+	return res, true
+}
+
+func (fs *FeedService) CacheStats() utils.CacheStats {
+	return fs.cache.Stats()
+}
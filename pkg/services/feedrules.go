@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// viewTypeRule is what a feed of a view type needs from each of its sections
+type viewTypeRule struct {
+	sectionTypes []string // allowed section types, "" is a plain image section
+	image        bool     // every section needs an image
+}
+
+var viewTypeRules = map[string]viewTypeRule{
+	"banner":   {sectionTypes: []string{"", "paralink", "course", "book"}, image: true},
+	"2dvstack": {sectionTypes: []string{"", "paralink", "course", "book", "options"}, image: true},
+	"vstack":   {sectionTypes: []string{"", "paralink", "course", "book", "options", "query"}},
+	"hstack":   {sectionTypes: []string{"", "paralink", "course", "book", "options", "query"}},
+}
+
+// SectionError is the validation error of the section at Path, for example
+// sections[2].options.sections[0].book
+type SectionError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// sectionRefs collects the ids and paralinks referenced by the sections and
+// where they are used so that each collection is queried once
+type sectionRefs struct {
+	ids       map[*mongo.Collection]map[primitive.ObjectID][]string
+	paralinks map[string][]string
+}
+
+func newSectionRefs() *sectionRefs {
+	return &sectionRefs{ids: map[*mongo.Collection]map[primitive.ObjectID][]string{}, paralinks: map[string][]string{}}
+}
+
+func (refs *sectionRefs) add(collection *mongo.Collection, id primitive.ObjectID, path string) {
+	if refs.ids[collection] == nil {
+		refs.ids[collection] = map[primitive.ObjectID][]string{}
+	}
+	refs.ids[collection][id] = append(refs.ids[collection][id], path)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func checkSections(sections []models.Section, rule viewTypeRule, prefix string, nested bool, refs *sectionRefs) []SectionError {
+	errs := []SectionError{}
+	for i, section := range sections {
+		path := fmt.Sprintf("%s[%d]", prefix, i)
+		fail := func(field string, message string) {
+			errs = append(errs, SectionError{Path: path + field, Message: message})
+		}
+		if !contains(rule.sectionTypes, section.Type) {
+			if section.Type == "" {
+				fail(".type", "type is required")
+			} else {
+				fail(".type", fmt.Sprintf("type should be one of %s", strings.Join(rule.sectionTypes[1:], ", ")))
+			}
+			continue
+		}
+		if (rule.image || section.Type == "") && section.Image.IsZero() {
+			fail(".image", "image is required")
+		}
+		if !section.Image.IsZero() {
+			refs.add(models.MediaCollection, section.Image, path+".image")
+		}
+		if section.Paralink != "" {
+			refs.paralinks[section.Paralink] = append(refs.paralinks[section.Paralink], path+".paralink")
+		}
+		switch section.Type {
+		case "paralink":
+			if section.Paralink == "" {
+				fail(".paralink", "paralink is required")
+			}
+		case "course":
+			if section.Course.IsZero() {
+				fail(".course", "course is required")
+			} else {
+				refs.add(models.CoursesCollection, section.Course, path+".course")
+			}
+		case "book":
+			if section.Book.IsZero() {
+				fail(".book", "book is required")
+			} else {
+				refs.add(models.BooksCollection, section.Book, path+".book")
+			}
+		case "query":
+			errs = append(errs, checkQuery(section.Query, path, refs)...)
+		case "options":
+			if nested {
+				fail(".type", "options can not be nested")
+			} else if len(section.Options.Sections) == 0 {
+				fail(".options.sections", "options should have atleast 1 section")
+			} else {
+				// options open as a list, so their sections do not need images
+				optionsRule := viewTypeRule{sectionTypes: viewTypeRules["vstack"].sectionTypes}
+				errs = append(errs, checkSections(section.Options.Sections, optionsRule, path+".options.sections", true, refs)...)
+			}
+		}
+	}
+	return errs
+}
+
+func checkQuery(query *models.SectionQuery, path string, refs *sectionRefs) []SectionError {
+	if query == nil {
+		return []SectionError{{Path: path + ".query", Message: "query is required"}}
+	}
+	errs := []SectionError{}
+	switch query.Kind {
+	case QUERY_NEW_ARRIVALS, QUERY_MOST_DISCOUNTED, QUERY_BEST_SELLERS:
+	case QUERY_COURSE:
+		if query.Course.IsZero() {
+			errs = append(errs, SectionError{Path: path + ".query.course", Message: "course is required"})
+		} else {
+			refs.add(models.CoursesCollection, query.Course, path+".query.course")
+		}
+	case QUERY_TAG:
+		if query.Tag == "" {
+			errs = append(errs, SectionError{Path: path + ".query.tag", Message: "tag is required"})
+		}
+	default:
+		errs = append(errs, SectionError{Path: path + ".query.kind", Message: "kind should be one of new_arrivals, most_discounted, best_sellers, course, tag"})
+	}
+	if query.Limit < 0 || query.Limit > MAX_QUERY_LIMIT {
+		errs = append(errs, SectionError{Path: path + ".query.limit", Message: fmt.Sprintf("limit should be atmost %d", MAX_QUERY_LIMIT)})
+	}
+	if query.Days < 0 {
+		errs = append(errs, SectionError{Path: path + ".query.days", Message: "days should not be negative"})
+	}
+	return errs
+}
+
+// validateFeed checks the sections of feed against the rules of its view type
+// and that every course, book, media and paralink they reference exists. The errors are
+// returned in Details, one per section path.
+func (fs *FeedService) validateFeed(ctx context.Context, feed *models.Feed) *utils.RestError {
+	rule, ok := viewTypeRules[feed.ViewType]
+	if feed.ViewType == "" {
+		rule = viewTypeRules["vstack"]
+	} else if !ok {
+		return utils.BadRequest("view_type should be one of banner, 2dvstack, vstack, hstack")
+	}
+
+	refs := newSectionRefs()
+	errs := checkSections(feed.Sections, rule, "sections", false, refs)
+	missing := []SectionError{}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	for collection, paths := range refs.ids {
+		ids := []primitive.ObjectID{}
+		for id := range paths {
+			ids = append(ids, id)
+		}
+		cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			return utils.InternalErr(err.Error())
+		}
+		var found []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err = cursor.All(ctx, &found); err != nil {
+			return utils.InternalErr(err.Error())
+		}
+		for _, doc := range found {
+			delete(paths, doc.ID)
+		}
+		for id, used := range paths {
+			for _, path := range used {
+				missing = append(missing, SectionError{Path: path, Message: fmt.Sprintf("%s %s not found", collection.Name(), id.Hex())})
+			}
+		}
+	}
+
+	if len(refs.paralinks) > 0 {
+		slugs := []string{}
+		for slug := range refs.paralinks {
+			slugs = append(slugs, slug)
+		}
+		registered, err := models.ParalinksCollection.Distinct(ctx, "slug", bson.M{"slug": bson.M{"$in": slugs}})
+		if err != nil {
+			return utils.InternalErr(err.Error())
+		}
+		for _, slug := range registered {
+			delete(refs.paralinks, slug.(string))
+		}
+		for slug, used := range refs.paralinks {
+			for _, path := range used {
+				missing = append(missing, SectionError{Path: path, Message: fmt.Sprintf("paralink %s is not registered", slug)})
+			}
+		}
+	}
+
+	sort.Slice(missing, func(i, j int) bool { return missing[i].Path < missing[j].Path })
+	errs = append(errs, missing...)
+	if len(errs) == 0 {
+		return nil
+	}
+	RestError := utils.BadRequest(fmt.Sprintf("%s: %s", errs[0].Path, errs[0].Message))
+	RestError.Details = errs
+	return RestError
+}
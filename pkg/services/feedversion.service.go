@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/hashicorp/go-hclog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var fvs *FeedVersionService
+
+type FeedVersionService struct {
+	fvc       *mongo.Collection
+	logger    hclog.Logger
+	configs   *utils.Configurations
+	validator *models.Validation
+}
+
+func NewFeedVersionService(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *FeedVersionService {
+	if fvs != nil {
+		return fvs
+	}
+	fvs = &FeedVersionService{models.FeedVersionsCollection, logger, configs, validator}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	_, err := fvs.fvc.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{"feed_id", 1}, {"version", -1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		logger.Error("unable to create feed version indexes", "error", err)
+	}
+	return fvs
+}
+
+// Snapshot stores feed as the next version of the feed. Two snapshots racing
+// for the same version number are retried against the unique index.
+func (fvs *FeedVersionService) Snapshot(ctx context.Context, feed *models.Feed, user primitive.ObjectID, draft bool) (*models.FeedVersion, *utils.RestError) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	for attempt := 0; attempt < 3; attempt++ {
+		var latest models.FeedVersion
+		opts := options.FindOne().SetSort(bson.D{{"version", -1}}).SetProjection(bson.M{"version": 1})
+		err := fvs.fvc.FindOne(ctx, bson.M{"feed_id": feed.ID}, opts).Decode(&latest)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return nil, utils.InternalErr(err.Error())
+		}
+		feedVersion := models.NewFeedVersion(&models.FeedVersion{
+			FeedID:    feed.ID,
+			Version:   latest.Version + 1,
+			Draft:     draft,
+			Feed:      *feed,
+			CreatedBy: user,
+		})
+		result, err := fvs.fvc.InsertOne(ctx, feedVersion)
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, utils.InternalErr("can't insert feed version to the database.")
+		}
+		feedVersion.ID = result.InsertedID.(primitive.ObjectID)
+		return feedVersion, nil
+	}
+	return nil, utils.InternalErr("can't allocate a feed version, try again.")
+}
+
+// Find lists the versions of a feed, newest first, without their sections
+func (fvs *FeedVersionService) Find(ctx context.Context, feed_id string, params *GetQuery) (bson.M, *utils.RestError) {
+	id, e := primitive.ObjectIDFromHex(feed_id)
+	if e != nil {
+		return nil, utils.NotFound("Invalid feed_id")
+	}
+	query := bson.M{"feed_id": id}
+	projectStage := bson.D{{"$project", bson.D{{"feed.sections", 0}}}}
+	sortBy := SortBy{"version", -1}
+	pipeline, after, RestError := Paginate(params, query, mongo.Pipeline{projectStage}, sortBy)
+	if RestError != nil {
+		return nil, RestError
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	cursor, err := fvs.fvc.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	var versions []bson.M
+	if err = cursor.All(ctx, &versions); err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	return PageResult(params, versions, sortBy, after), nil
+}
+
+func (fvs *FeedVersionService) FindByVersion(ctx context.Context, feed_id string, version int) (*models.FeedVersion, *utils.RestError) {
+	id, e := primitive.ObjectIDFromHex(feed_id)
+	if e != nil {
+		return nil, utils.NotFound("Invalid feed_id")
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	var feedVersion models.FeedVersion
+	err := fvs.fvc.FindOne(ctx, bson.M{"feed_id": id, "version": version}).Decode(&feedVersion)
+	if err != nil {
+		return nil, utils.NotFound("version not found.")
+	}
+	return &feedVersion, nil
+}
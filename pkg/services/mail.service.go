@@ -1,10 +1,19 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"time"
+
 	"github.com/SairamVemula/booksland-backend-go/pkg/models"
 	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
 	"github.com/hashicorp/go-hclog"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MailService interface {
@@ -16,19 +25,154 @@ type MailService interface {
 	verifyForgetPassword(user *models.User, code string) error
 }
 
+var _ MailService = (*Mail)(nil)
+
 var ms *Mail
 
 type Mail struct {
 	vc        *mongo.Collection
 	fpc       *mongo.Collection
+	pvc       *mongo.Collection
 	logger    hclog.Logger
 	configs   *utils.Configurations
 	validator *models.Validation
+	us        *UserService
+	mailer    utils.Mailer
+	sms       utils.SMSProvider
 }
 
 func NewMailService(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *Mail {
 	if ms != nil {
 		return ms
 	}
-	return &Mail{models.DB.Collection("verification"), models.DB.Collection("forgotpassword"), logger, configs, validator}
+	mailer, err := utils.NewMailer(logger, configs)
+	if err != nil {
+		logger.Error("unable to open the mailer", "driver", configs.MailDriver, "error", err)
+		os.Exit(1)
+	}
+	sms, err := utils.NewSMSProvider(logger, configs)
+	if err != nil {
+		logger.Error("unable to open the sms provider", "driver", configs.SMSDriver, "error", err)
+		os.Exit(1)
+	}
+	ms = &Mail{models.DB.Collection("verification"), models.DB.Collection("forgotpassword"), models.DB.Collection("phoneverification"), logger, configs, validator, NewUserService(logger, configs, validator), mailer, sms}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	_, err = ms.vc.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"user_id", 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{"expires_at", 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		logger.Error("unable to create verification indexes", "error", err)
+	}
+	_, err = ms.fpc.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"user_id", 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{"token_hash", 1}}},
+		{Keys: bson.D{{"expires_at", 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		logger.Error("unable to create password reset indexes", "error", err)
+	}
+	// keyed by the number so the throttling holds across accounts
+	_, err = ms.pvc.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"phone", 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{"expires_at", 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		logger.Error("unable to create phone verification indexes", "error", err)
+	}
+	return ms
+}
+
+// send renders the mail name for the user and hands it to the mailer
+func (ms *Mail) send(ctx context.Context, user *models.User, name string, data map[string]interface{}) error {
+	template, ok := mailTemplates[name]
+	if !ok {
+		return fmt.Errorf("unknown mail template %s", name)
+	}
+	data["AppName"], data["Name"], data["Email"] = ms.configs.MailFromName, user.Name, user.Email
+	subject, text, html, err := template.render(data)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*15)
+	defer cancel()
+	return ms.mailer.Send(ctx, &utils.MailMessage{
+		From:    (&mail.Address{Name: ms.configs.MailFromName, Address: ms.configs.MailFrom}).String(),
+		To:      user.Email,
+		ToName:  user.Name,
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+	})
+}
+
+// sendMail mails the email verification code
+func (ms *Mail) sendMail(user *models.User, code string) error {
+	return ms.send(context.Background(), user, MAIL_VERIFICATION, map[string]interface{}{
+		"Code":    code,
+		"Expires": ms.configs.MailVerifCodeExpiration,
+	})
+}
+
+// sendSMS texts the phone verification code
+func (ms *Mail) sendSMS(user *models.User, code string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+	text := fmt.Sprintf("%s is your %s verification code. It expires in %d minutes.", code, ms.configs.MailFromName, ms.configs.PhoneOtpExpiration)
+	return ms.sms.Send(ctx, utils.PhoneNumber(user.Phone, ms.configs.SMSCountryCode), text)
+}
+
+// verify checks the email verification code of the user
+func (ms *Mail) verify(user *models.User, code string) error {
+	if RestError := ms.VerifyEmail(context.Background(), user.Email, code); RestError != nil {
+		return errors.New(RestError.Message)
+	}
+	return nil
+}
+
+// sendForgotPasswordMail mails the password reset token
+func (ms *Mail) sendForgotPasswordMail(user *models.User, code string) error {
+	return ms.send(context.Background(), user, MAIL_PASSWORD_RESET, map[string]interface{}{
+		"Token":   code,
+		"Expires": ms.configs.PassResetCodeExpiration,
+	})
+}
+
+// sendForgotPasswordSMS texts the password reset token
+func (ms *Mail) sendForgotPasswordSMS(user *models.User, code string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	defer cancel()
+	text := fmt.Sprintf("Your %s password reset token is %s. It expires in %d minutes.", ms.configs.MailFromName, code, ms.configs.PassResetCodeExpiration)
+	return ms.sms.Send(ctx, utils.PhoneNumber(user.Phone, ms.configs.SMSCountryCode), text)
+}
+
+// verifyForgetPassword checks that code is an unexpired reset token of the
+// user without using it up
+func (ms *Mail) verifyForgetPassword(user *models.User, code string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	count, err := ms.fpc.CountDocuments(ctx, bson.M{"user_id": user.ID, "token_hash": hashToken(code), "expires_at": bson.M{"$gt": time.Now()}})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("the reset token is invalid or expired")
+	}
+	return nil
+}
+
+// SendOrderConfirmation mails the user that the order was placed
+func (ms *Mail) SendOrderConfirmation(ctx context.Context, user *models.User, order *models.Order) error {
+	return ms.send(ctx, user, MAIL_ORDER_CONFIRMATION, map[string]interface{}{"Order": order})
+}
+
+// SendShippingUpdate mails the user the new status of the order, trackingUrl
+// may be empty
+func (ms *Mail) SendShippingUpdate(ctx context.Context, user *models.User, order *models.Order, status string, trackingUrl string) error {
+	return ms.send(ctx, user, MAIL_SHIPPING_UPDATE, map[string]interface{}{
+		"Order":       order,
+		"Status":      status,
+		"TrackingUrl": trackingUrl,
+	})
 }
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates/mail
+var mailTemplateFS embed.FS
+
+// the mails sent, each has <name>.html and <name>.txt under templates/mail
+const (
+	MAIL_VERIFICATION       = "verification"
+	MAIL_PASSWORD_RESET     = "password_reset"
+	MAIL_ORDER_CONFIRMATION = "order_confirmation"
+	MAIL_SHIPPING_UPDATE    = "shipping_update"
+)
+
+// mailSubjects are text templates over the same data as the bodies
+var mailSubjects = map[string]string{
+	MAIL_VERIFICATION:       "Your {{.AppName}} verification code is {{.Code}}",
+	MAIL_PASSWORD_RESET:     "Reset your {{.AppName}} password",
+	MAIL_ORDER_CONFIRMATION: "Your {{.AppName}} order {{.Order.ID.Hex}} is confirmed",
+	MAIL_SHIPPING_UPDATE:    "Your {{.AppName}} order {{.Order.ID.Hex}} is {{.Status}}",
+}
+
+type mailTemplate struct {
+	subject *texttemplate.Template
+	html    *htmltemplate.Template
+	text    *texttemplate.Template
+}
+
+// mailTemplates are parsed once at startup, a broken template panics there
+// instead of failing a mail later
+var mailTemplates = parseMailTemplates()
+
+func parseMailTemplates() map[string]*mailTemplate {
+	templates := map[string]*mailTemplate{}
+	for name, subject := range mailSubjects {
+		templates[name] = &mailTemplate{
+			subject: texttemplate.Must(texttemplate.New(name).Option("missingkey=error").Parse(subject)),
+			html:    htmltemplate.Must(htmltemplate.ParseFS(mailTemplateFS, "templates/mail/layout.html", "templates/mail/"+name+".html")),
+			text:    texttemplate.Must(texttemplate.ParseFS(mailTemplateFS, "templates/mail/"+name+".txt")),
+		}
+	}
+	return templates
+}
+
+// render fills the subject and both bodies of the mail name, data gets the
+// Subject for the HTML title
+func (t *mailTemplate) render(data map[string]interface{}) (subject string, text string, html string, err error) {
+	var buf bytes.Buffer
+	if err = t.subject.Execute(&buf, data); err != nil {
+		return
+	}
+	subject = strings.TrimSpace(buf.String())
+	data["Subject"] = subject
+
+	buf.Reset()
+	if err = t.text.Execute(&buf, data); err != nil {
+		return
+	}
+	text = buf.String()
+
+	buf.Reset()
+	if err = t.html.ExecuteTemplate(&buf, "layout", data); err != nil {
+		return
+	}
+	html = buf.String()
+	return
+}
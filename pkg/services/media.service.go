@@ -2,7 +2,11 @@ package services
 
 import (
 	"context"
-	"os"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/SairamVemula/booksland-backend-go/pkg/models"
@@ -11,6 +15,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var mds *MediaService
@@ -20,28 +25,156 @@ type MediaService struct {
 	logger    hclog.Logger
 	configs   *utils.Configurations
 	validator *models.Validation
+	searchLog *SearchLogService
+	cache     utils.Cache
+	store     utils.BlobStore
 }
 
 func NewMediaService(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *MediaService {
-	return &MediaService{models.MediaCollection, logger, configs, validator}
+	if mds != nil {
+		return mds
+	}
+	mds = &MediaService{models.MediaCollection, logger, configs, validator, NewSearchLogService(logger, configs, validator), NewFeedCache(logger, configs), NewMediaStore(logger, configs)}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	_, err := mds.mdc.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{"hash", 1}}, Options: options.Index().SetUnique(true).SetSparse(true)})
+	if err != nil {
+		logger.Error("unable to create media indexes", "error", err)
+	}
+	if !utils.WebpSupported {
+		logger.Warn("built without cgo, the image variants are only written as jpeg and webp requests get those")
+	}
+	return mds
+}
+
+// CreateFromUpload creates the media of an uploaded file, or reuses the media
+// of the same content. Private media are never shared, so they keep no hash.
+func (mds *MediaService) CreateFromUpload(ctx context.Context, uploaded *utils.UploadedFile, alt string, private bool, user_id primitive.ObjectID) (*models.Media, *utils.RestError) {
+	if uploaded.Duplicate {
+		mds.logger.Debug("upload already stored", "hash", uploaded.Hash)
+		return mds.Reuse(ctx, uploaded.Hash)
+	}
+	media := &models.Media{
+		Path:      uploaded.Path,
+		Hash:      uploaded.Hash,
+		MimeType:  uploaded.ContentType,
+		Size:      uploaded.Size,
+		Width:     uploaded.Width,
+		Height:    uploaded.Height,
+		Alt:       alt,
+		Private:   private,
+		CreatedBy: user_id,
+	}
+	if private {
+		media.Hash = ""
+	}
+	media = models.NewMedia(media)
+	if err := mds.validator.Struct(media); err != nil {
+		mds.store.Delete(ctx, utils.BlobKey(media.Path))
+		return nil, utils.BadRequest(err.Errors()[0])
+	}
+	return mds.Create(ctx, media)
+}
+
+// HasHash reports whether a media with the content hash exists, it is the
+// lookup uploads use to skip writing duplicates
+func (mds *MediaService) HasHash(ctx context.Context, hash string) bool {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	count, err := mds.mdc.CountDocuments(ctx, bson.M{"hash": hash})
+	if err != nil {
+		mds.logger.Error("unable to look up media hash", "error", err)
+		return false
+	}
+	return count > 0
+}
+
+// Reuse returns the media with the content hash for another upload of it,
+// taking a reference so deleting one of the uploads keeps the media
+func (mds *MediaService) Reuse(ctx context.Context, hash string) (*models.Media, *utils.RestError) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	after := options.After
+	opts := options.FindOneAndUpdateOptions{ReturnDocument: &after}
+	update := bson.M{"$inc": bson.M{"ref_count": 1}, "$set": bson.M{"updated_on": time.Now().UnixMilli()}}
+	var media models.Media
+	err := mds.mdc.FindOneAndUpdate(ctx, bson.M{"hash": hash}, update, &opts).Decode(&media)
+	if err != nil {
+		return nil, utils.NotFound("media not found.")
+	}
+	mds.appendUrls(&media)
+	return &media, nil
+}
+
+func (mds *MediaService) appendUrls(media *models.Media) {
+	// private media are only reached through signed urls
+	if media.Private {
+		return
+	}
+	media.Path = mds.configs.AppendUrl(media.Path)
+	for i := range media.Variants {
+		media.Variants[i].Path = mds.configs.AppendUrl(media.Variants[i].Path)
+	}
 }
 
 func (mds *MediaService) Create(ctx context.Context, media *models.Media) (*models.Media, *utils.RestError) {
 	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
 	defer cancel()
 
+	variants, err := utils.SaveImageVariants(ctx, mds.store, media.Path)
+	if err != nil {
+		mds.store.Delete(ctx, utils.BlobKey(media.Path))
+		return nil, utils.BadRequest(err.Error())
+	}
+	for _, variant := range variants {
+		media.Variants = append(media.Variants, models.MediaVariant{Size: variant.Size, Format: variant.Format, Path: variant.Path, Width: variant.Width, Height: variant.Height})
+	}
+
 	result, err := mds.mdc.InsertOne(ctx, media)
+	if mongo.IsDuplicateKeyError(err) {
+		// the same content was uploaded at the same time, keep the first one
+		utils.RemoveImageVariants(ctx, mds.store, variants)
+		mds.store.Delete(ctx, utils.BlobKey(media.Path))
+		return mds.Reuse(ctx, media.Hash)
+	}
 	if err != nil {
+		utils.RemoveImageVariants(ctx, mds.store, variants)
+		mds.store.Delete(ctx, utils.BlobKey(media.Path))
 		RestError := utils.InternalErr("can't insert user to the database.")
 		return nil, RestError
 	}
 	media.ID = result.InsertedID.(primitive.ObjectID)
-	media.Path = mds.configs.AppendUrl(media.Path)
+	mds.appendUrls(media)
 	return media, nil
 }
 
-func (mds *MediaService) Find(ctx context.Context, params *GetQuery) (bson.M, *utils.RestError) {
-	skip := (params.Page - 1) * params.Limit
+// MediaQuery is the query of the media list, the dimensions are in pixels
+type MediaQuery struct {
+	GetQuery
+	Type      string `schema:"type"` // mime type such as image/png, or only its subtype
+	MinWidth  int    `schema:"min_width"`
+	MaxWidth  int    `schema:"max_width"`
+	MinHeight int    `schema:"min_height"`
+	MaxHeight int    `schema:"max_height"`
+	Private   bool   `schema:"private"` // lists the private media instead, admins only
+}
+
+// dimension filters a field between min and max, 0 leaves that side open
+func dimension(query bson.M, field string, min int, max int) {
+	filter := bson.M{}
+	if min > 0 {
+		filter["$gte"] = min
+	}
+	if max > 0 {
+		filter["$lte"] = max
+	}
+	if len(filter) > 0 {
+		query[field] = filter
+	}
+}
+
+func (mds *MediaService) Find(ctx context.Context, mediaQuery *MediaQuery) (bson.M, *utils.RestError) {
+	params := &mediaQuery.GetQuery
 	query := bson.M{}
 	// opts := options.Find().SetSkip(skip).SetLimit(params.Limit)
 
@@ -52,10 +185,31 @@ func (mds *MediaService) Find(ctx context.Context, params *GetQuery) (bson.M, *u
 			bson.M{
 				"path": bson.M{"$regex": params.Search, "$options": "i"},
 			},
+			bson.M{
+				"alt": bson.M{"$regex": params.Search, "$options": "i"},
+			},
+		}
+	}
+	if mediaQuery.Type != "" {
+		mimeType := strings.ToLower(mediaQuery.Type)
+		if !strings.Contains(mimeType, "/") {
+			mimeType = "image/" + mimeType
 		}
+		query["mime_type"] = mimeType
 	}
+	if mediaQuery.MinWidth > 0 && mediaQuery.MaxWidth > 0 && mediaQuery.MinWidth > mediaQuery.MaxWidth {
+		return nil, utils.BadRequest("min_width should not be more than max_width")
+	}
+	if mediaQuery.MinHeight > 0 && mediaQuery.MaxHeight > 0 && mediaQuery.MinHeight > mediaQuery.MaxHeight {
+		return nil, utils.BadRequest("min_height should not be more than max_height")
+	}
+	query["private"] = bson.M{"$ne": true}
+	if mediaQuery.Private {
+		query["private"] = true
+	}
+	dimension(query, "width", mediaQuery.MinWidth, mediaQuery.MaxWidth)
+	dimension(query, "height", mediaQuery.MinHeight, mediaQuery.MaxHeight)
 
-	matchStage := bson.D{{"$match", query}}
 	// projectStage := bson.D{
 	// 	{"$project", bson.D{
 	// 		{"_id", 1},
@@ -66,21 +220,12 @@ func (mds *MediaService) Find(ctx context.Context, params *GetQuery) (bson.M, *u
 	// 	},
 	// 	},
 	// }
-	setStage := bson.D{{Key: "$addFields", Value: bson.M{"url": bson.D{{"$concat", bson.A{mds.configs.AssetsUrl, "$path"}}}}}}
-	sortStage := bson.D{{Key: "$sort", Value: bson.D{{"_id", -1}}}}
-	skipStage := bson.D{{Key: "$skip", Value: skip}}
-	limitStage := bson.D{{Key: "$limit", Value: params.Limit}}
-	countStage := bson.D{{Key: "$count", Value: "count"}}
-
-	facetStage := bson.D{{
-		"$facet", bson.D{
-			{"docs", bson.A{sortStage, skipStage, limitStage}},
-			{"total", bson.A{countStage}},
-		},
-	}}
-	unwindStage := bson.D{{"$unwind", "$total"}}
-
-	pipeline := mongo.Pipeline{matchStage, setStage, facetStage, unwindStage}
+	setStage := bson.D{{Key: "$addFields", Value: mediaUrlFields(mds.configs.AssetsUrl, "")}}
+	sortBy := SortBy{"_id", -1}
+	pipeline, after, RestError := Paginate(params, query, mongo.Pipeline{setStage}, sortBy)
+	if RestError != nil {
+		return nil, RestError
+	}
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
 	defer cancel()
@@ -96,10 +241,9 @@ func (mds *MediaService) Find(ctx context.Context, params *GetQuery) (bson.M, *u
 		RestError := utils.InternalErr("Internal Server Error")
 		return nil, RestError
 	}
-	if len(medias) == 0 {
-		return bson.M{"docs": []bson.M{}, "total": bson.M{"count": 0}}, nil
-	}
-	return medias[0], nil
+	res := PageResult(params, medias, sortBy, after)
+	mds.searchLog.Log(params, "media", res)
+	return res, nil
 }
 func (mds *MediaService) FindById(ctx context.Context, media_id string) (*models.Media, *utils.RestError) {
 	var media models.Media
@@ -118,6 +262,98 @@ func (mds *MediaService) FindById(ctx context.Context, media_id string) (*models
 	return &media, nil
 }
 
+// Open reads the blob at path of a media
+func (mds *MediaService) Open(ctx context.Context, path string) (io.ReadSeekCloser, *utils.BlobInfo, *utils.RestError) {
+	blob, info, err := mds.store.Get(ctx, utils.BlobKey(path))
+	if err == utils.ErrBlobNotFound {
+		return nil, nil, utils.NotFound("media file not found.")
+	}
+	if err != nil {
+		return nil, nil, utils.InternalErr(err.Error())
+	}
+	return blob, info, nil
+}
+
+// Store is the blob store the uploads are written to
+func (mds *MediaService) Store() utils.BlobStore {
+	return mds.store
+}
+
+// UpdateById sets the editorial fields of a media
+func (mds *MediaService) UpdateById(ctx context.Context, media_id string, updateMedia *models.UpdateMedia) (*models.Media, *utils.RestError) {
+	id, e := primitive.ObjectIDFromHex(media_id)
+	if e != nil {
+		return nil, utils.NotFound("Invalid media_id")
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	updateMedia.UpdatedOn = time.Now().UnixMilli()
+	after := options.After
+	opts := options.FindOneAndUpdateOptions{ReturnDocument: &after}
+	var media models.Media
+	err := mds.mdc.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": updateMedia}, &opts).Decode(&media)
+	if err == mongo.ErrNoDocuments {
+		return nil, utils.NotFound("media not found.")
+	}
+	if err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	mds.cache.Invalidate(ctx, MediaTag(id))
+	mds.appendUrls(&media)
+	return &media, nil
+}
+
+// SignUrl returns a url of a private media that works until it expires, the
+// admins and the uploader can sign it. A bound url only opens for user.
+func (mds *MediaService) SignUrl(ctx context.Context, media_id string, sign *models.SignMedia, user *models.User) (*models.SignedMedia, *utils.RestError) {
+	media, RestError := mds.FindById(ctx, media_id)
+	if RestError != nil {
+		return nil, RestError
+	}
+	if !media.Private {
+		return nil, utils.BadRequest("media is public, it does not need a signed url")
+	}
+	if user.Type != "admin" && media.CreatedBy != user.ID {
+		return nil, &utils.RestError{Code: http.StatusForbidden, Message: "Forbbiden Access", Error: "forbidden"}
+	}
+	minutes := sign.ExpiresIn
+	if minutes == 0 {
+		minutes = mds.configs.MediaUrlExpiration
+	}
+	expires := time.Now().Add(time.Minute * time.Duration(minutes)).Unix()
+	query := url.Values{"expires": {strconv.FormatInt(expires, 10)}}
+	user_id := ""
+	if sign.BindUser {
+		user_id = user.ID.Hex()
+		query.Set("user", user_id)
+	}
+	query.Set("signature", utils.SignMedia(mds.configs.MediaSigningKey, media.ID.Hex(), user_id, expires))
+	return &models.SignedMedia{Url: "/media/" + media.ID.Hex() + "?" + query.Encode(), ExpiresAt: expires * 1000}, nil
+}
+
+// CanOpen reports whether the request may read the media, private media need
+// a valid signature and, when the url is bound, the user it was signed for
+func (mds *MediaService) CanOpen(media *models.Media, query url.Values, user *models.User) *utils.RestError {
+	if !media.Private {
+		return nil
+	}
+	forbidden := &utils.RestError{Code: http.StatusForbidden, Message: "the url of a private media should be signed", Error: "forbidden"}
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return forbidden
+	}
+	user_id := query.Get("user")
+	if !utils.VerifyMedia(mds.configs.MediaSigningKey, media.ID.Hex(), user_id, expires, query.Get("signature")) {
+		forbidden.Message = "the signed url is invalid or expired"
+		return forbidden
+	}
+	if user_id != "" && (user == nil || user.ID.Hex() != user_id) {
+		forbidden.Message = "the signed url belongs to another user"
+		return forbidden
+	}
+	return nil
+}
+
 func (mds *MediaService) DeleteById(ctx context.Context, media_id string) *utils.RestError {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
@@ -126,26 +362,35 @@ func (mds *MediaService) DeleteById(ctx context.Context, media_id string) *utils
 		RestError := utils.NotFound("Invalid media_id")
 		return RestError
 	}
+	// every upload of the same content holds a reference, the files go with the last one
+	after := options.After
+	opts := options.FindOneAndUpdateOptions{ReturnDocument: &after}
 	var media models.Media
-	err := cs.cc.FindOne(ctx, bson.M{"_id": id}).Decode(&media)
+	err := mds.mdc.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"ref_count": -1}}, &opts).Decode(&media)
 	if err != nil {
 		RestError := utils.NotFound("media not found")
 		return RestError
 	}
-	err = os.Remove("." + media.Path)
-	if err != nil {
-		RestError := utils.NotFound(err.Error())
-		return RestError
+	if media.RefCount > 0 {
+		return nil
 	}
 
-	result, err := cs.cc.DeleteOne(ctx, bson.M{"_id": id})
+	// an upload of the same content may have taken a reference meanwhile
+	result, err := mds.mdc.DeleteOne(ctx, bson.M{"_id": id, "ref_count": bson.M{"$lte": 0}})
 	if err != nil {
 		RestError := utils.NotFound("media to delete.")
 		return RestError
 	}
 	if result.DeletedCount == 0 {
-		RestError := utils.NotFound("media not found.")
-		return RestError
+		return nil
+	}
+	err = mds.store.Delete(ctx, utils.BlobKey(media.Path))
+	if err != nil && err != utils.ErrBlobNotFound {
+		mds.logger.Error("unable to delete media file", "path", media.Path, "error", err)
+	}
+	for _, variant := range media.Variants {
+		mds.store.Delete(ctx, utils.BlobKey(variant.Path))
 	}
+	mds.cache.Invalidate(ctx, MediaTag(id))
 	return nil
 }
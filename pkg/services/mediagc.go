@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/hashicorp/go-hclog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MEDIA_PREFIX is where the uploads are kept in the blob store
+const MEDIA_PREFIX = "assets/"
+
+// PRIVATE_PREFIX is where the private uploads are kept, never served directly
+const PRIVATE_PREFIX = "private/"
+
+// mediaRefs lists the fields that reference media. Feed versions are included
+// so a rollback never brings back a feed whose images were collected.
+var mediaRefs = []struct {
+	collection string
+	field      string
+}{
+	{"books", "image"},
+	{"courses", "image"},
+	{"feeds", "sections.image"},
+	{"feeds", "sections.options.sections.image"},
+	{"feed_versions", "feed.sections.image"},
+	{"feed_versions", "feed.sections.options.sections.image"},
+}
+
+type OrphanReport struct {
+	DryRun      bool              `json:"dry_run"`
+	GracePeriod string            `json:"grace_period"`
+	Media       []models.Media    `json:"media"`
+	Files       []*utils.BlobInfo `json:"files"`
+	Bytes       int64             `json:"bytes"` // size of the orphaned files
+	Deleted     int               `json:"deleted"`
+	Errors      []string          `json:"errors,omitempty"`
+}
+
+var mgs *MediaGCService
+
+type MediaGCService struct {
+	mdc       *mongo.Collection
+	logger    hclog.Logger
+	configs   *utils.Configurations
+	validator *models.Validation
+	store     utils.BlobStore
+	cache     utils.Cache
+}
+
+func NewMediaGCService(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *MediaGCService {
+	if mgs != nil {
+		return mgs
+	}
+	mgs = &MediaGCService{models.MediaCollection, logger, configs, validator, NewMediaStore(logger, configs), NewFeedCache(logger, configs)}
+	return mgs
+}
+
+// Start collects the orphaned media now and then on every interval, only
+// reporting them unless MEDIA_GC_DRY_RUN is turned off
+func (mgs *MediaGCService) Start(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	grace := time.Hour * time.Duration(mgs.configs.MediaGCGracePeriod)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			report, RestError := mgs.Collect(context.Background(), mgs.configs.MediaGCDryRun, grace)
+			if RestError != nil {
+				mgs.logger.Error("unable to collect orphaned media", "error", RestError.Message)
+			} else {
+				mgs.logger.Info("collected orphaned media", "dry_run", report.DryRun, "media", len(report.Media), "files", len(report.Files), "bytes", report.Bytes, "deleted", report.Deleted)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// referencedMedia returns the ids of every media used by a document
+func (mgs *MediaGCService) referencedMedia(ctx context.Context) (map[primitive.ObjectID]bool, error) {
+	ids := map[primitive.ObjectID]bool{}
+	for _, ref := range mediaRefs {
+		values, err := models.DB.Collection(ref.collection).Distinct(ctx, ref.field, bson.M{ref.field: bson.M{"$exists": true}})
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			if id, ok := value.(primitive.ObjectID); ok {
+				ids[id] = true
+			}
+		}
+	}
+	return ids, nil
+}
+
+// isReferenced checks a single media again right before it is deleted
+func (mgs *MediaGCService) isReferenced(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	for _, ref := range mediaRefs {
+		count, err := models.DB.Collection(ref.collection).CountDocuments(ctx, bson.M{ref.field: id})
+		if err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Report finds the media no document references and the files no media owns,
+// leaving out everything younger than grace
+func (mgs *MediaGCService) Report(ctx context.Context, grace time.Duration) (*OrphanReport, *utils.RestError) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	cutoff := time.Now().Add(-grace)
+	report := &OrphanReport{DryRun: true, GracePeriod: grace.String(), Media: []models.Media{}, Files: []*utils.BlobInfo{}}
+
+	referenced, err := mgs.referencedMedia(ctx)
+	if err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+
+	cursor, err := mgs.mdc.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	defer cursor.Close(ctx)
+	owned := map[string]bool{}
+	for cursor.Next(ctx) {
+		var media models.Media
+		if err := cursor.Decode(&media); err != nil {
+			return nil, utils.InternalErr(err.Error())
+		}
+		owned[utils.BlobKey(media.Path)] = true
+		for _, variant := range media.Variants {
+			owned[utils.BlobKey(variant.Path)] = true
+		}
+		// private media belong to their uploader, no document references them
+		if !media.Private && !referenced[media.ID] && media.CreatedOn < cutoff.UnixMilli() {
+			report.Media = append(report.Media, media)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+
+	// the chunks of expired uploads stay behind when mongo drops the upload
+	uploads, err := models.UploadsCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	defer uploads.Close(ctx)
+	for uploads.Next(ctx) {
+		var upload models.Upload
+		if err := uploads.Decode(&upload); err != nil {
+			return nil, utils.InternalErr(err.Error())
+		}
+		for _, chunk := range upload.Chunks {
+			owned[chunk.Key] = true
+		}
+	}
+
+	for _, prefix := range []string{MEDIA_PREFIX, PRIVATE_PREFIX, UPLOADS_PREFIX} {
+		err = mgs.store.List(ctx, prefix, func(blob *utils.BlobInfo) error {
+			if !owned[blob.Key] && blob.ModTime.Before(cutoff) {
+				report.Files = append(report.Files, blob)
+				report.Bytes += blob.Size
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, utils.InternalErr(err.Error())
+		}
+	}
+	sort.Slice(report.Files, func(i, j int) bool { return report.Files[i].Key < report.Files[j].Key })
+	return report, nil
+}
+
+// Collect deletes what Report finds unless dryRun is set
+func (mgs *MediaGCService) Collect(ctx context.Context, dryRun bool, grace time.Duration) (*OrphanReport, *utils.RestError) {
+	report, RestError := mgs.Report(ctx, grace)
+	if RestError != nil || dryRun {
+		return report, RestError
+	}
+	report.DryRun = false
+	ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
+	defer cancel()
+
+	for _, media := range report.Media {
+		used, err := mgs.isReferenced(ctx, media.ID)
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		if used {
+			continue
+		}
+		result, err := mgs.mdc.DeleteOne(ctx, bson.M{"_id": media.ID, "private": bson.M{"$ne": true}})
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		if result.DeletedCount == 0 {
+			continue
+		}
+		keys := []string{utils.BlobKey(media.Path)}
+		for _, variant := range media.Variants {
+			keys = append(keys, utils.BlobKey(variant.Path))
+		}
+		for _, key := range keys {
+			if err := mgs.store.Delete(ctx, key); err != nil && err != utils.ErrBlobNotFound {
+				report.Errors = append(report.Errors, err.Error())
+			}
+		}
+		mgs.cache.Invalidate(ctx, MediaTag(media.ID))
+		report.Deleted++
+	}
+
+	for _, blob := range report.Files {
+		// a media may have been created for the file since the report
+		collection, filter := mgs.mdc, bson.M{"$or": bson.A{
+			bson.M{"path": "/" + blob.Key},
+			bson.M{"variants.path": "/" + blob.Key},
+		}}
+		if strings.HasPrefix(blob.Key, UPLOADS_PREFIX) {
+			collection, filter = models.UploadsCollection, bson.M{"chunks.key": blob.Key}
+		}
+		count, err := collection.CountDocuments(ctx, filter)
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		if count > 0 {
+			continue
+		}
+		if err := mgs.store.Delete(ctx, blob.Key); err != nil && err != utils.ErrBlobNotFound {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		report.Deleted++
+	}
+	return report, nil
+}
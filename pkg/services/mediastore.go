@@ -0,0 +1,25 @@
+package services
+
+import (
+	"os"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/hashicorp/go-hclog"
+)
+
+var mediaStore utils.BlobStore
+
+// NewMediaStore returns the blob store of the uploaded media. A store that can
+// not be opened stops the server, writing to another backend would lose media.
+func NewMediaStore(logger hclog.Logger, configs *utils.Configurations) utils.BlobStore {
+	if mediaStore != nil {
+		return mediaStore
+	}
+	store, err := utils.NewBlobStore(logger, configs)
+	if err != nil {
+		logger.Error("unable to open the media store", "driver", configs.BlobDriver, "error", err)
+		os.Exit(1)
+	}
+	mediaStore = store
+	return mediaStore
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MAX_LIMIT caps the page size a client can ask for on the list endpoints
+const MAX_LIMIT = 100
+
+// Cursor is the decoded form of the opaque next_cursor/prev_cursor tokens.
+// It holds the sort key and _id of the document the next page starts after.
+type Cursor struct {
+	Key  interface{}        `bson:"k"`
+	ID   primitive.ObjectID `bson:"i"`
+	Prev bool               `bson:"p,omitempty"`
+}
+
+func EncodeCursor(c *Cursor) string {
+	raw, err := bson.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	var c Cursor
+	if err := bson.Unmarshal(raw, &c); err != nil {
+		return nil, err
+	}
+	// the key goes into the match as is, a document could smuggle in operators
+	switch c.Key.(type) {
+	case nil, string, bool, int32, int64, float64, primitive.DateTime, primitive.ObjectID, primitive.Decimal128:
+	default:
+		return nil, errors.New("the cursor key should be a scalar")
+	}
+	return &c, nil
+}
+
+// SortBy is the sort key of a list endpoint, _id is always added as a tie breaker
+type SortBy struct {
+	Key string
+	Dir int
+}
+
+func (s SortBy) stage(dir int) bson.D {
+	if s.Key == "_id" {
+		return bson.D{{"$sort", bson.D{{"_id", dir}}}}
+	}
+	return bson.D{{"$sort", bson.D{{s.Key, dir}, {"_id", dir}}}}
+}
+
+// after returns the match condition for documents that come after c when
+// walking the sort in direction dir. A missing key sorts before any value.
+func (s SortBy) after(c *Cursor, dir int) bson.M {
+	op := "$gt"
+	if dir < 0 {
+		op = "$lt"
+	}
+	if s.Key == "_id" {
+		return bson.M{"_id": bson.M{op: c.ID}}
+	}
+	if c.Key == nil {
+		if dir > 0 {
+			return bson.M{"$or": bson.A{
+				bson.M{s.Key: bson.M{"$ne": nil}},
+				bson.M{s.Key: nil, "_id": bson.M{"$gt": c.ID}},
+			}}
+		}
+		return bson.M{s.Key: nil, "_id": bson.M{"$lt": c.ID}}
+	}
+	or := bson.A{
+		bson.M{s.Key: bson.M{op: c.Key}},
+		bson.M{s.Key: c.Key, "_id": bson.M{op: c.ID}},
+	}
+	if dir < 0 {
+		or = append(or, bson.M{s.Key: nil})
+	}
+	return bson.M{"$or": or}
+}
+
+func (s SortBy) cursorFor(doc bson.M, prev bool) string {
+	id, _ := doc["_id"].(primitive.ObjectID)
+	c := &Cursor{ID: id, Prev: prev}
+	if s.Key != "_id" {
+		c.Key = doc[s.Key]
+	}
+	return EncodeCursor(c)
+}
+
+// Paginate wraps the lookup stages of a list endpoint with either page-number
+// paging ($skip) or cursor paging when params.Cursor is set. In cursor mode the
+// cursor condition, sort and limit run before the lookups so deep pages stay cheap.
+func Paginate(params *GetQuery, query bson.M, stages mongo.Pipeline, sort SortBy) (mongo.Pipeline, *Cursor, *utils.RestError) {
+	if params.Cursor == "" {
+		skip := (params.Page - 1) * params.Limit
+		pipeline := mongo.Pipeline{bson.D{{"$match", query}}}
+		pipeline = append(pipeline, stages...)
+		facetStage := bson.D{{
+			"$facet", bson.D{
+				{"docs", bson.A{sort.stage(sort.Dir), bson.D{{"$skip", skip}}, bson.D{{"$limit", params.Limit}}}},
+				{"total", bson.A{bson.D{{"$count", "count"}}}},
+			},
+		}}
+		return append(pipeline, facetStage, bson.D{{"$unwind", "$total"}}), nil, nil
+	}
+
+	c, err := DecodeCursor(params.Cursor)
+	if err != nil {
+		return nil, nil, utils.BadRequest("Invalid cursor")
+	}
+	dir := sort.Dir
+	if c.Prev {
+		dir = -dir
+	}
+	pipeline := mongo.Pipeline{
+		bson.D{{"$match", bson.M{"$and": bson.A{query, sort.after(c, dir)}}}},
+		sort.stage(dir),
+		bson.D{{"$limit", params.Limit + 1}},
+	}
+	pipeline = append(pipeline, stages...)
+	return append(pipeline, sort.stage(dir)), c, nil
+}
+
+// PageResult shapes the aggregation output of a pipeline built by Paginate
+// into the list response with docs, total (page mode) and the cursor tokens.
+func PageResult(params *GetQuery, results []bson.M, sort SortBy, c *Cursor) bson.M {
+	if c == nil {
+		if len(results) == 0 {
+			return bson.M{"docs": []bson.M{}, "total": bson.M{"count": 0}}
+		}
+		res := results[0]
+		docs, _ := res["docs"].(bson.A)
+		if total := resultCount(res); len(docs) > 0 && (params.Page-1)*params.Limit+int64(len(docs)) < total {
+			if last, ok := docs[len(docs)-1].(bson.M); ok {
+				res["next_cursor"] = sort.cursorFor(last, false)
+			}
+		}
+		return res
+	}
+
+	hasMore := int64(len(results)) > params.Limit
+	if hasMore {
+		results = results[:params.Limit]
+	}
+	if c.Prev {
+		for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+			results[i], results[j] = results[j], results[i]
+		}
+	}
+	res := bson.M{"docs": results}
+	if len(results) == 0 {
+		res["docs"] = []bson.M{}
+		return res
+	}
+	first, last := results[0], results[len(results)-1]
+	if !c.Prev || hasMore {
+		res["prev_cursor"] = sort.cursorFor(first, true)
+	}
+	if c.Prev || hasMore {
+		res["next_cursor"] = sort.cursorFor(last, false)
+	}
+	return res
+}
+
+// resultCount reads the number of matching documents out of a list response
+func resultCount(res bson.M) int64 {
+	if total, ok := res["total"].(bson.M); ok {
+		switch count := total["count"].(type) {
+		case int32:
+			return int64(count)
+		case int64:
+			return count
+		case int:
+			return int64(count)
+		}
+	}
+	if docs, ok := res["docs"].([]bson.M); ok {
+		return int64(len(docs))
+	}
+	if docs, ok := res["docs"].(bson.A); ok {
+		return int64(len(docs))
+	}
+	return 0
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/hashicorp/go-hclog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var slugPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+var pls *ParalinkService
+
+type ParalinkService struct {
+	plc       *mongo.Collection
+	logger    hclog.Logger
+	configs   *utils.Configurations
+	validator *models.Validation
+}
+
+func NewParalinkService(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *ParalinkService {
+	if pls != nil {
+		return pls
+	}
+	pls = &ParalinkService{models.ParalinksCollection, logger, configs, validator}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	_, err := pls.plc.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{"slug", 1}}, Options: options.Index().SetUnique(true)})
+	if err != nil {
+		logger.Error("unable to create paralink indexes", "error", err)
+	}
+	return pls
+}
+
+// checkTarget makes sure the target of a paralink is usable for its type
+func (pls *ParalinkService) checkTarget(ctx context.Context, targetType string, target string) *utils.RestError {
+	switch targetType {
+	case "course", "book":
+		id, err := primitive.ObjectIDFromHex(target)
+		if err != nil {
+			return utils.BadRequest(fmt.Sprintf("target should be a %s id", targetType))
+		}
+		collection := models.CoursesCollection
+		if targetType == "book" {
+			collection = models.BooksCollection
+		}
+		count, err := collection.CountDocuments(ctx, bson.M{"_id": id})
+		if err != nil {
+			return utils.InternalErr(err.Error())
+		}
+		if count == 0 {
+			return utils.NotFound(fmt.Sprintf("%s not found.", targetType))
+		}
+	case "url":
+		if !utils.IsUrl(target) {
+			return utils.BadRequest("target should be a valid url")
+		}
+	}
+	return nil
+}
+
+func (pls *ParalinkService) Create(ctx context.Context, paralink *models.Paralink) (*models.Paralink, *utils.RestError) {
+	if !slugPattern.MatchString(paralink.Slug) {
+		return nil, utils.BadRequest("slug should only have letters, numbers, - and _")
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+	if RestError := pls.checkTarget(ctx, paralink.TargetType, paralink.Target); RestError != nil {
+		return nil, RestError
+	}
+	paralink = models.NewParalink(paralink)
+	result, err := pls.plc.InsertOne(ctx, paralink)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, utils.BadRequest("slug is already taken")
+	}
+	if err != nil {
+		return nil, utils.InternalErr("can't insert paralink to the database.")
+	}
+	paralink.ID = result.InsertedID.(primitive.ObjectID)
+	return paralink, nil
+}
+
+func (pls *ParalinkService) Find(ctx context.Context, params *GetQuery) (bson.M, *utils.RestError) {
+	query := bson.M{}
+	if params.Search != "" {
+		query["$or"] = bson.A{
+			bson.M{"slug": bson.M{"$regex": regexp.QuoteMeta(params.Search), "$options": "i"}},
+			bson.M{"title": bson.M{"$regex": regexp.QuoteMeta(params.Search), "$options": "i"}},
+		}
+	}
+	sortBy := SortBy{"slug", 1}
+	pipeline, after, RestError := Paginate(params, query, mongo.Pipeline{}, sortBy)
+	if RestError != nil {
+		return nil, RestError
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	cursor, err := pls.plc.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	var paralinks []bson.M
+	if err = cursor.All(ctx, &paralinks); err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	return PageResult(params, paralinks, sortBy, after), nil
+}
+
+func (pls *ParalinkService) FindBySlug(ctx context.Context, slug string) (*models.Paralink, *utils.RestError) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	var paralink models.Paralink
+	err := pls.plc.FindOne(ctx, bson.M{"slug": slug}).Decode(&paralink)
+	if err != nil {
+		return nil, utils.NotFound("paralink not found.")
+	}
+	return &paralink, nil
+}
+
+// Resolve returns the paralink with the entity it points to. Feeds are picked
+// the way GET /feeds?paralink= picks them, with the experiment variants of
+// subject.
+func (pls *ParalinkService) Resolve(ctx context.Context, slug string, user_id primitive.ObjectID, subject string) (bson.M, *utils.RestError) {
+	paralink, RestError := pls.FindBySlug(ctx, slug)
+	if RestError != nil {
+		return nil, RestError
+	}
+	var target interface{}
+	switch paralink.TargetType {
+	case "feed":
+		params := &GetQuery{Paralink: paralink.Target, Status: FEED_STATUS_LIVE, UserID: user_id}
+		NewGetQuery(params)
+		params.Limit = MAX_LIMIT
+		if RestError = NewExperimentService(pls.logger, pls.configs, pls.validator).Assign(ctx, params, subject); RestError != nil {
+			return nil, RestError
+		}
+		target, RestError = NewFeedService(pls.logger, pls.configs, pls.validator).Find(ctx, params)
+	case "course":
+		target, RestError = pls.course(ctx, paralink.Target)
+	case "book":
+		target, RestError = NewBookService(pls.logger, pls.configs, pls.validator).FindById(ctx, paralink.Target)
+	case "search":
+		// following a link is not a search a user typed
+		params := &GetQuery{Search: paralink.Target, SkipSearchLog: true}
+		NewGetQuery(params)
+		target, RestError = NewBookService(pls.logger, pls.configs, pls.validator).Find(ctx, params)
+	case "url":
+		target = paralink.Target
+	}
+	if RestError != nil {
+		return nil, RestError
+	}
+	return bson.M{"paralink": paralink, "target_type": paralink.TargetType, "target": target}, nil
+}
+
+// course loads a course with its image
+func (pls *ParalinkService) course(ctx context.Context, course_id string) (bson.M, *utils.RestError) {
+	id, e := primitive.ObjectIDFromHex(course_id)
+	if e != nil {
+		return nil, utils.NotFound("Invalid course_id")
+	}
+	pipeline := mongo.Pipeline{bson.D{{"$match", bson.M{"_id": id}}}}
+	pipeline = append(pipeline, imageLookupStages(pls.configs.AssetsUrl, "image", "image")...)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	cursor, err := models.CoursesCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	var courses []bson.M
+	if err = cursor.All(ctx, &courses); err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	if len(courses) == 0 {
+		return nil, utils.NotFound("course not found.")
+	}
+	return courses[0], nil
+}
+
+func (pls *ParalinkService) UpdateBySlug(ctx context.Context, slug string, updateParalink *models.UpdateParalink) (*models.Paralink, *utils.RestError) {
+	paralink, RestError := pls.FindBySlug(ctx, slug)
+	if RestError != nil {
+		return nil, RestError
+	}
+	targetType, target := paralink.TargetType, paralink.Target
+	if updateParalink.TargetType != "" {
+		targetType = updateParalink.TargetType
+	}
+	if updateParalink.Target != "" {
+		target = updateParalink.Target
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	if RestError := pls.checkTarget(ctx, targetType, target); RestError != nil {
+		return nil, RestError
+	}
+	updateParalink.UpdatedOn = time.Now().UnixMilli()
+	after := options.After
+	opts := options.FindOneAndUpdateOptions{ReturnDocument: &after}
+	result := pls.plc.FindOneAndUpdate(ctx, bson.M{"_id": paralink.ID}, bson.M{"$set": updateParalink}, &opts)
+	if result.Err() != nil {
+		return nil, utils.InternalErr(result.Err().Error())
+	}
+	if err := result.Decode(paralink); err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	return paralink, nil
+}
+
+// DeleteBySlug deletes a paralink that no feed section uses anymore
+func (pls *ParalinkService) DeleteBySlug(ctx context.Context, slug string) *utils.RestError {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	used, err := models.FeedsCollection.CountDocuments(ctx, bson.M{"$or": bson.A{
+		bson.M{"sections.paralink": slug},
+		bson.M{"sections.options.sections.paralink": slug},
+	}})
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+	if used > 0 {
+		return utils.BadRequest(fmt.Sprintf("paralink is used by %d feeds", used))
+	}
+	result, err := pls.plc.DeleteOne(ctx, bson.M{"slug": slug})
+	if err != nil {
+		return utils.InternalErr("faild to delete.")
+	}
+	if result.DeletedCount == 0 {
+		return utils.NotFound("paralink not found.")
+	}
+	return nil
+}
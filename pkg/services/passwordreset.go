@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ForgotPassword mails a reset token to email. Nothing tells the caller
+// whether the email is registered, not even the response time, so the work
+// runs in the background and failures are only logged.
+func (ms *Mail) ForgotPassword(email string) {
+	go ms.forgotPassword(email)
+}
+
+func (ms *Mail) forgotPassword(email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+	user, RestError := ms.us.FindByEmail(ctx, email)
+	if RestError != nil {
+		return
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		ms.logger.Error("unable to generate reset token", "error", err)
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	now := time.Now()
+	// a token asked for within the resend interval does not match, the upsert
+	// then runs into the unique user_id and no mail goes out
+	filter := bson.M{"user_id": user.ID, "created_on": bson.M{"$lte": now.Add(-VERIFICATION_RESEND_INTERVAL).UnixMilli()}}
+	update := bson.M{"$set": bson.M{
+		"token_hash": hashToken(token),
+		"expires_at": now.Add(time.Minute * time.Duration(ms.configs.PassResetCodeExpiration)),
+		"created_on": now.UnixMilli(),
+	}}
+	_, err := ms.fpc.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return
+	}
+	if err != nil {
+		ms.logger.Error("unable to store reset token", "user", user.ID.Hex(), "error", err)
+		return
+	}
+	if err := ms.sendForgotPasswordMail(user, token); err != nil {
+		ms.logger.Error("unable to send reset mail", "user", user.ID.Hex(), "error", err)
+	}
+}
+
+// ResetPassword sets the password of the user the token was sent to. The
+// token is used up and every refresh token of the user is revoked.
+func (ms *Mail) ResetPassword(ctx context.Context, token string, password string) *utils.RestError {
+	invalid := utils.BadRequest("the reset token is invalid or expired")
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	var reset models.PasswordReset
+	filter := bson.M{"token_hash": hashToken(token), "expires_at": bson.M{"$gt": time.Now()}}
+	err := ms.fpc.FindOneAndDelete(ctx, filter).Decode(&reset)
+	if err == mongo.ErrNoDocuments {
+		return invalid
+	}
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), 10)
+	if err != nil {
+		return utils.InternalErr("Internal Server Error")
+	}
+	update := bson.M{
+		"$set":   bson.M{"password": string(hash), "updated_on": time.Now().UnixMilli()},
+		"$unset": bson.M{"refresh_token": "", "refresh_token_expiry": ""},
+	}
+	result, err := models.UsersCollection.UpdateOne(ctx, bson.M{"_id": reset.UserID}, update)
+	if err != nil {
+		return utils.InternalErr("can not update.")
+	}
+	if result.MatchedCount == 0 {
+		return invalid
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SendPhoneOtp texts a new verification code to the phone of the user. The
+// limits of the email codes apply to each number.
+func (ms *Mail) SendPhoneOtp(ctx context.Context, user *models.User) *utils.RestError {
+	if user.Verified.Phone {
+		return utils.BadRequest("phone is already verified")
+	}
+	if user.Phone == "" {
+		return utils.BadRequest("phone is required")
+	}
+	code, err := generateCode()
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), 10)
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	now := time.Now()
+	filter := bson.M{
+		"phone":   user.Phone,
+		"sent_on": bson.M{"$lte": now.Add(-VERIFICATION_RESEND_INTERVAL).UnixMilli()},
+		"sends":   bson.M{"$lt": VERIFICATION_MAX_SENDS},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"user_id":    user.ID,
+			"code_hash":  string(hash),
+			"attempts":   0,
+			"sent_on":    now.UnixMilli(),
+			"expires_at": now.Add(time.Minute * time.Duration(ms.configs.PhoneOtpExpiration)),
+			"updated_on": now.UnixMilli(),
+		},
+		"$inc":         bson.M{"sends": 1},
+		"$setOnInsert": bson.M{"created_on": now.UnixMilli()},
+	}
+	_, err = ms.pvc.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return tooManyRequests("a code was sent to this number recently, please wait before asking for another")
+	}
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+	if err := ms.sendSMS(user, code); err != nil {
+		ms.logger.Error("unable to send verification sms", "user", user.ID.Hex(), "error", err)
+		return utils.InternalErr("unable to send the verification sms")
+	}
+	return nil
+}
+
+// VerifyPhone checks the code texted to the user and marks the phone verified
+func (ms *Mail) VerifyPhone(ctx context.Context, user *models.User, code string) *utils.RestError {
+	if user.Verified.Phone {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	// the attempt is counted before the code is compared. The TTL index only
+	// drops expired codes about every minute, so the expiry is checked here.
+	var verification models.Verification
+	now := time.Now()
+	live := bson.M{"phone": user.Phone, "user_id": user.ID, "expires_at": bson.M{"$gt": now}}
+	filter := bson.M{"phone": user.Phone, "user_id": user.ID, "expires_at": bson.M{"$gt": now}, "attempts": bson.M{"$lt": VERIFICATION_MAX_ATTEMPTS}}
+	err := ms.pvc.FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"attempts": 1}}).Decode(&verification)
+	if err == mongo.ErrNoDocuments {
+		count, _ := ms.pvc.CountDocuments(ctx, live)
+		if count > 0 {
+			return tooManyRequests("too many wrong codes, please ask for a new one")
+		}
+		return invalidCode()
+	}
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+	if bcrypt.CompareHashAndPassword([]byte(verification.CodeHash), []byte(code)) != nil {
+		return invalidCode()
+	}
+
+	result, err := models.UsersCollection.UpdateOne(ctx, bson.M{"_id": user.ID, "phone": verification.Phone}, bson.M{"$set": bson.M{"verified.phone": true, "updated_on": time.Now().UnixMilli()}})
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+	if result.MatchedCount == 0 {
+		return invalidCode()
+	}
+	if _, err := ms.pvc.DeleteOne(ctx, bson.M{"_id": verification.ID}); err != nil {
+		ms.logger.Error("unable to delete phone verification", "user", user.ID.Hex(), "error", err)
+	}
+	return nil
+}
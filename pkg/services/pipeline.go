@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// imageLookupStages joins the media document referenced by field into as
+// and adds its url the same way the list pipelines do
+func imageLookupStages(assetsUrl string, field string, as string) mongo.Pipeline {
+	mediaLookup := bson.D{{
+		"$lookup", bson.D{
+			{"from", "media"},
+			{"let", bson.M{"image_id": "$" + field}},
+			{"pipeline", bson.A{
+				bson.D{{
+					"$match", bson.D{{
+						"$expr",
+						bson.D{{
+							"$and",
+							bson.A{
+								bson.D{{"$eq", bson.A{"$_id", "$$image_id"}}},
+							},
+						}},
+					}},
+				}},
+			},
+			},
+			{"as", as},
+		},
+	}}
+	mediaUnWind := bson.D{{"$unwind", bson.D{{"path", "$" + as}, {"preserveNullAndEmptyArrays", true}}}}
+	mediaConcat := bson.D{{Key: "$addFields", Value: mediaUrlFields(assetsUrl, as+".")}}
+	return mongo.Pipeline{mediaLookup, mediaUnWind, mediaConcat}
+}
+
+// mediaUrlFields adds the url of the media at prefix and of each of its variants
+func mediaUrlFields(assetsUrl string, prefix string) bson.M {
+	// private media get no public url, they are opened through signed urls
+	publicUrl := func(path string) bson.D {
+		return bson.D{{"$cond", bson.A{
+			bson.D{{"$eq", bson.A{"$" + prefix + "private", true}}},
+			"$$REMOVE",
+			bson.D{{"$concat", bson.A{assetsUrl, path}}},
+		}}}
+	}
+	return bson.M{
+		prefix + "url": publicUrl("$" + prefix + "path"),
+		prefix + "variants": bson.D{{"$map", bson.D{
+			{"input", bson.D{{"$ifNull", bson.A{"$" + prefix + "variants", bson.A{}}}}},
+			{"as", "variant"},
+			{"in", bson.D{{"$mergeObjects", bson.A{"$$variant", bson.M{"url": publicUrl("$$variant.path")}}}}},
+		}}},
+	}
+}
+
+// stockSummaryLookup groups the available stocks of a book by publisher and year
+func stockSummaryLookup() bson.D {
+	return bson.D{{
+		"$lookup", bson.D{
+			{"from", "stocks"},
+			{"let", bson.M{"book_id": "$_id"}},
+			{"pipeline", bson.A{
+				bson.D{{
+					"$match", bson.D{{
+						"$expr",
+						bson.D{{
+							"$and",
+							bson.A{
+								bson.D{{"$eq", bson.A{"$book_id", "$$book_id"}}},
+								bson.D{{"$eq", bson.A{"$status", "available"}}},
+							},
+						}},
+					}},
+				}},
+				bson.D{{
+					"$group", bson.D{
+						{"_id", bson.D{{"publisher", "$publisher"}, {"year", "$year"}}},
+						{"prices", bson.D{{"$push", "$price"}}},
+						{"discount_percents", bson.D{{"$push", "$discount_percent"}}},
+						{"count", bson.D{{"$sum", 1}}},
+					},
+				}},
+			},
+			},
+			{"as", "stocks"},
+		},
+	}}
+}
+
+// hydrateBooks loads the books in ids with their image and stock summary,
+// keeping the order of ids
+func hydrateBooks(ctx context.Context, assetsUrl string, ids []primitive.ObjectID) ([]bson.M, error) {
+	if len(ids) == 0 {
+		return []bson.M{}, nil
+	}
+	pipeline := mongo.Pipeline{bson.D{{"$match", bson.M{"_id": bson.M{"$in": ids}}}}}
+	pipeline = append(pipeline, imageLookupStages(assetsUrl, "image", "image")...)
+	pipeline = append(pipeline, stockSummaryLookup())
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	cursor, err := models.BooksCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var books []bson.M
+	if err = cursor.All(ctx, &books); err != nil {
+		return nil, err
+	}
+	byID := map[primitive.ObjectID]bson.M{}
+	for _, book := range books {
+		if id, ok := book["_id"].(primitive.ObjectID); ok {
+			byID[id] = book
+		}
+	}
+	ordered := []bson.M{}
+	for _, id := range ids {
+		if book, ok := byID[id]; ok {
+			ordered = append(ordered, book)
+		}
+	}
+	return ordered, nil
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/hashicorp/go-hclog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// a book bought together counts more than a book carted together
+	orderPairWeight = 2
+	cartPairWeight  = 1
+	// number of recommendations stored for every book
+	maxRecommendations = 20
+)
+
+var rcs *RecommendationService
+
+type RecommendationService struct {
+	rc        *mongo.Collection
+	logger    hclog.Logger
+	configs   *utils.Configurations
+	validator *models.Validation
+}
+
+func NewRecommendationService(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *RecommendationService {
+	if rcs != nil {
+		return rcs
+	}
+	rcs = &RecommendationService{models.RecommendationsCollection, logger, configs, validator}
+	return rcs
+}
+
+// Start computes the recommendations now and then on every interval
+func (rcs *RecommendationService) Start(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := rcs.Compute(context.Background()); err != nil {
+				rcs.logger.Error("unable to compute recommendations", "error", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// pairStages expands a list of distinct book ids in $books into every ordered
+// pair of different books and counts how often each pair occurs
+func pairStages() mongo.Pipeline {
+	return mongo.Pipeline{
+		bson.D{{"$match", bson.M{"books.1": bson.M{"$exists": true}}}},
+		bson.D{{"$project", bson.D{{"a", "$books"}, {"b", "$books"}}}},
+		bson.D{{"$unwind", "$a"}},
+		bson.D{{"$unwind", "$b"}},
+		bson.D{{"$match", bson.D{{"$expr", bson.D{{"$ne", bson.A{"$a", "$b"}}}}}}},
+		bson.D{{"$group", bson.D{{"_id", bson.D{{"a", "$a"}, {"b", "$b"}}}, {"count", bson.D{{"$sum", 1}}}}}},
+	}
+}
+
+func (rcs *RecommendationService) countPairs(ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline, weight int, scores map[primitive.ObjectID]map[primitive.ObjectID]int) error {
+	cursor, err := collection.Aggregate(ctx, append(pipeline, pairStages()...))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var pair struct {
+			ID struct {
+				A primitive.ObjectID `bson:"a"`
+				B primitive.ObjectID `bson:"b"`
+			} `bson:"_id"`
+			Count int `bson:"count"`
+		}
+		if err := cursor.Decode(&pair); err != nil {
+			return err
+		}
+		if scores[pair.ID.A] == nil {
+			scores[pair.ID.A] = map[primitive.ObjectID]int{}
+		}
+		scores[pair.ID.A][pair.ID.B] += pair.Count * weight
+	}
+	return cursor.Err()
+}
+
+// Compute materialises the recommendations collection from the books bought
+// together in orders and the books sitting together in users carts
+func (rcs *RecommendationService) Compute(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute*10)
+	defer cancel()
+	started := time.Now().UnixMilli()
+	scores := map[primitive.ObjectID]map[primitive.ObjectID]int{}
+
+	// unpaid and abandoned orders are not purchases
+	orderBooks := mongo.Pipeline{
+		bson.D{{"$match", bson.M{"status": models.PaymentStatusPaid}}},
+		bson.D{{"$project", bson.D{{"books", bson.D{{"$setUnion", bson.A{"$items.book_id", bson.A{}}}}}}}},
+	}
+	if err := rcs.countPairs(ctx, models.OrdersCollection, orderBooks, orderPairWeight, scores); err != nil {
+		return err
+	}
+	cartBooks := mongo.Pipeline{
+		bson.D{{"$group", bson.D{{"_id", "$user_id"}, {"books", bson.D{{"$addToSet", "$book_id"}}}}}},
+	}
+	if err := rcs.countPairs(ctx, models.CartItemsCollection, cartBooks, cartPairWeight, scores); err != nil {
+		return err
+	}
+
+	writes := []mongo.WriteModel{}
+	for bookID, related := range scores {
+		books := []models.RecommendedBook{}
+		for relatedID, score := range related {
+			books = append(books, models.RecommendedBook{BookID: relatedID, Score: score})
+		}
+		sort.Slice(books, func(i, j int) bool {
+			if books[i].Score == books[j].Score {
+				return books[i].BookID.Hex() < books[j].BookID.Hex()
+			}
+			return books[i].Score > books[j].Score
+		})
+		if len(books) > maxRecommendations {
+			books = books[:maxRecommendations]
+		}
+		recommendation := models.NewRecommendation(&models.Recommendation{ID: bookID, Books: books})
+		writes = append(writes, mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": bookID}).SetReplacement(recommendation).SetUpsert(true))
+	}
+	if len(writes) > 0 {
+		if _, err := rcs.rc.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+	// books that lost all their pairs since the last run
+	_, err := rcs.rc.DeleteMany(ctx, bson.M{"updated_on": bson.M{"$lt": started}})
+	if err != nil {
+		return err
+	}
+	rcs.logger.Debug("recommendations computed", "books", len(writes))
+	return nil
+}
+
+// FindByBook returns the recommended books for book_id, filled up with other
+// books of the same course when there are not enough co-purchases
+func (rcs *RecommendationService) FindByBook(ctx context.Context, book_id string, limit int64) (bson.M, *utils.RestError) {
+	id, e := primitive.ObjectIDFromHex(book_id)
+	if e != nil {
+		return nil, utils.NotFound("Invalid book_id")
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	var book models.Book
+	if err := models.BooksCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&book); err != nil {
+		return nil, utils.NotFound("book not found.")
+	}
+
+	ids := []primitive.ObjectID{}
+	var recommendation models.Recommendation
+	err := rcs.rc.FindOne(ctx, bson.M{"_id": id}).Decode(&recommendation)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, utils.InternalErr(err.Error())
+	}
+	for _, recommended := range recommendation.Books {
+		if int64(len(ids)) == limit {
+			break
+		}
+		ids = append(ids, recommended.BookID)
+	}
+
+	if int64(len(ids)) < limit && !book.CourseID.IsZero() {
+		exclude := append([]primitive.ObjectID{id}, ids...)
+		opts := options.Find().SetSort(bson.D{{"order", 1}, {"_id", 1}}).SetLimit(limit - int64(len(ids))).SetProjection(bson.M{"_id": 1})
+		cursor, err := models.BooksCollection.Find(ctx, bson.M{"course_id": book.CourseID, "_id": bson.M{"$nin": exclude}}, opts)
+		if err != nil {
+			return nil, utils.InternalErr(err.Error())
+		}
+		defer cursor.Close(ctx)
+		for cursor.Next(ctx) {
+			var sameCourse models.Book
+			if err := cursor.Decode(&sameCourse); err != nil {
+				return nil, utils.InternalErr(err.Error())
+			}
+			ids = append(ids, sameCourse.ID)
+		}
+	}
+
+	books, err := hydrateBooks(ctx, rcs.configs.AssetsUrl, ids)
+	if err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	return bson.M{"docs": books}, nil
+}
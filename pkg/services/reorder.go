@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// reorder checks that every id of reorder exists in collection and then sets
+// their order in a single bulk write. It returns the parsed ids.
+func reorder(ctx context.Context, collection *mongo.Collection, reorder *models.Reorder) ([]primitive.ObjectID, bson.M, *utils.RestError) {
+	ids := []primitive.ObjectID{}
+	seen := map[primitive.ObjectID]bool{}
+	for _, hex := range reorder.IDs {
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			return nil, nil, utils.BadRequest(fmt.Sprintf("Invalid id %s", hex))
+		}
+		if seen[id] {
+			return nil, nil, utils.BadRequest(fmt.Sprintf("id %s is repeated", hex))
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, nil, utils.InternalErr(err.Error())
+	}
+	var found []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err = cursor.All(ctx, &found); err != nil {
+		return nil, nil, utils.InternalErr(err.Error())
+	}
+	if len(found) != len(ids) {
+		for _, doc := range found {
+			delete(seen, doc.ID)
+		}
+		missing := []string{}
+		for _, id := range ids {
+			if seen[id] {
+				missing = append(missing, id.Hex())
+			}
+		}
+		RestError := utils.NotFound(fmt.Sprintf("%s not found: %s", collection.Name(), strings.Join(missing, ", ")))
+		RestError.Details = missing
+		return nil, nil, RestError
+	}
+
+	now := time.Now().UnixMilli()
+	writes := []mongo.WriteModel{}
+	for i, id := range ids {
+		update := bson.M{"$set": bson.M{"order": i + 1, "updated_on": now}}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": id}).SetUpdate(update))
+	}
+	result, err := collection.BulkWrite(ctx, writes)
+	if err != nil {
+		return nil, nil, utils.InternalErr(err.Error())
+	}
+	return ids, bson.M{"matched_count": result.MatchedCount, "modified_count": result.ModifiedCount}, nil
+}
@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/hashicorp/go-hclog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var rvs *ReviewService
+
+type ReviewService struct {
+	rvc       *mongo.Collection
+	logger    hclog.Logger
+	configs   *utils.Configurations
+	validator *models.Validation
+	cache     utils.Cache
+	// a standalone mongo has no transactions, the writes then run one by one
+	transactions bool
+}
+
+func NewReviewService(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *ReviewService {
+	if rvs != nil {
+		return rvs
+	}
+	rvs = &ReviewService{models.ReviewsCollection, logger, configs, validator, NewFeedCache(logger, configs), false}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	rvs.transactions = supportsTransactions(ctx)
+	if !rvs.transactions {
+		logger.Warn("mongo is not a replica set, the review writes and the book rating are updated without a transaction")
+	}
+	_, err := rvs.rvc.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"book_id", 1}, {"user_id", 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{"book_id", 1}, {"status", 1}, {"created_on", -1}}},
+	})
+	if err != nil {
+		logger.Error("unable to create review indexes", "error", err)
+	}
+	return rvs
+}
+
+// supportsTransactions tells if the server is a replica set member or a
+// mongos, transactions are not allowed on a standalone server
+func supportsTransactions(ctx context.Context) bool {
+	var hello bson.M
+	if err := models.DB.RunCommand(ctx, bson.D{{"isMaster", 1}}).Decode(&hello); err != nil {
+		return false
+	}
+	_, replicaSet := hello["setName"]
+	return replicaSet || hello["msg"] == "isdbgrid"
+}
+
+// transaction runs fn and then recomputes the rating of book_id in one
+// transaction, or one after the other when mongo has no transactions
+func (rvs *ReviewService) transaction(ctx context.Context, book_id primitive.ObjectID, fn func(sc mongo.SessionContext) *utils.RestError) *utils.RestError {
+	session, err := models.DB.Client().StartSession()
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	var RestError *utils.RestError
+	run := func(sc mongo.SessionContext) (interface{}, error) {
+		if RestError = fn(sc); RestError != nil {
+			return nil, errors.New(RestError.Message)
+		}
+		return nil, rvs.updateRating(sc, book_id)
+	}
+	if rvs.transactions {
+		_, err = session.WithTransaction(ctx, run)
+	} else {
+		err = mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
+			_, err := run(sc)
+			return err
+		})
+	}
+	if RestError != nil {
+		return RestError
+	}
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+	// the rating of the book is rendered in the feeds
+	rvs.cache.Invalidate(ctx, BOOKS_TAG, BookTag(book_id))
+	return nil
+}
+
+// updateRating stores the average and count of the published reviews on the book
+func (rvs *ReviewService) updateRating(ctx context.Context, book_id primitive.ObjectID) error {
+	matchStage := bson.D{{"$match", bson.M{"book_id": book_id, "status": models.ReviewStatusPublished}}}
+	groupStage := bson.D{{"$group", bson.D{{"_id", nil}, {"average", bson.D{{"$avg", "$rating"}}}, {"count", bson.D{{"$sum", 1}}}}}}
+	cursor, err := rvs.rvc.Aggregate(ctx, mongo.Pipeline{matchStage, groupStage})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var ratings []models.Rating
+	if err = cursor.All(ctx, &ratings); err != nil {
+		return err
+	}
+	rating := models.Rating{}
+	if len(ratings) > 0 {
+		rating = ratings[0]
+		rating.Average = math.Round(rating.Average*100) / 100
+	}
+	_, err = models.BooksCollection.UpdateOne(ctx, bson.M{"_id": book_id}, bson.M{"$set": bson.M{"rating": rating}})
+	return err
+}
+
+func (rvs *ReviewService) Create(ctx context.Context, book_id string, user *models.User, review *models.Review) (*models.Review, *utils.RestError) {
+	id, e := primitive.ObjectIDFromHex(book_id)
+	if e != nil {
+		return nil, utils.NotFound("Invalid book_id")
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+	count, err := models.BooksCollection.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	if count == 0 {
+		return nil, utils.NotFound("book not found.")
+	}
+	// only paid orders count, PaymentStatus is stored as status
+	orders, err := models.OrdersCollection.CountDocuments(ctx, bson.M{"created_by": user.ID, "items.book_id": id, "status": models.PaymentStatusPaid})
+	if err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+
+	review = models.NewReview(&models.Review{
+		BookID:           id,
+		UserID:           user.ID,
+		Rating:           review.Rating,
+		Text:             review.Text,
+		VerifiedPurchase: orders > 0,
+	})
+	RestError := rvs.transaction(ctx, id, func(sc mongo.SessionContext) *utils.RestError {
+		result, err := rvs.rvc.InsertOne(sc, review)
+		if mongo.IsDuplicateKeyError(err) {
+			return utils.BadRequest("You have already reviewed this book")
+		}
+		if err != nil {
+			return utils.InternalErr("can't insert review to the database.")
+		}
+		review.ID = result.InsertedID.(primitive.ObjectID)
+		return nil
+	})
+	if RestError != nil {
+		return nil, RestError
+	}
+	return review, nil
+}
+
+// Find lists the reviews of a book with the name of their users. Only admins
+// can list reviews that are not published.
+func (rvs *ReviewService) Find(ctx context.Context, book_id string, params *GetQuery, admin bool) (bson.M, *utils.RestError) {
+	id, e := primitive.ObjectIDFromHex(book_id)
+	if e != nil {
+		return nil, utils.NotFound("Invalid book_id")
+	}
+	query := bson.M{"book_id": id}
+	if !admin {
+		query["status"] = models.ReviewStatusPublished
+	} else if params.Status != "" {
+		query["status"] = params.Status
+	}
+
+	userLookup := bson.D{{
+		"$lookup", bson.D{
+			{"from", "users"},
+			{"let", bson.M{"user_id": "$user_id"}},
+			{"pipeline", bson.A{
+				bson.D{{
+					"$match", bson.D{{
+						"$expr",
+						bson.D{{"$eq", bson.A{"$_id", "$$user_id"}}},
+					}},
+				}},
+				bson.D{{"$project", bson.D{{"_id", 1}, {"name", 1}}}},
+			},
+			},
+			{"as", "user"},
+		},
+	}}
+	userUnwind := bson.D{{"$unwind", bson.D{{"path", "$user"}, {"preserveNullAndEmptyArrays", true}}}}
+	sortBy := SortBy{"created_on", -1}
+	pipeline, after, RestError := Paginate(params, query, mongo.Pipeline{userLookup, userUnwind}, sortBy)
+	if RestError != nil {
+		return nil, RestError
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	cursor, err := rvs.rvc.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	var reviews []bson.M
+	if err = cursor.All(ctx, &reviews); err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	return PageResult(params, reviews, sortBy, after), nil
+}
+
+func (rvs *ReviewService) FindById(ctx context.Context, review_id string) (*models.Review, *utils.RestError) {
+	var review models.Review
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	id, e := primitive.ObjectIDFromHex(review_id)
+	if e != nil {
+		return nil, utils.NotFound("Invalid review_id")
+	}
+	err := rvs.rvc.FindOne(ctx, bson.M{"_id": id}).Decode(&review)
+	if err != nil {
+		return nil, utils.NotFound("review not found.")
+	}
+	return &review, nil
+}
+
+func (rvs *ReviewService) update(ctx context.Context, review *models.Review, update interface{}) (*models.Review, *utils.RestError) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+	RestError := rvs.transaction(ctx, review.BookID, func(sc mongo.SessionContext) *utils.RestError {
+		after := options.After
+		opts := options.FindOneAndUpdateOptions{ReturnDocument: &after}
+		result := rvs.rvc.FindOneAndUpdate(sc, bson.M{"_id": review.ID}, bson.M{"$set": update}, &opts)
+		if result.Err() != nil {
+			return utils.InternalErr(result.Err().Error())
+		}
+		if err := result.Decode(review); err != nil {
+			return utils.InternalErr(err.Error())
+		}
+		return nil
+	})
+	if RestError != nil {
+		return nil, RestError
+	}
+	return review, nil
+}
+
+// UpdateById lets a user edit the rating and text of their own review
+func (rvs *ReviewService) UpdateById(ctx context.Context, review_id string, user *models.User, updateReview *models.UpdateReview) (*models.Review, *utils.RestError) {
+	review, RestError := rvs.FindById(ctx, review_id)
+	if RestError != nil {
+		return nil, RestError
+	}
+	if review.UserID != user.ID {
+		return nil, &utils.RestError{Code: http.StatusForbidden, Message: "You can only edit your own review", Error: "forbidden"}
+	}
+	updateReview.UpdatedOn = time.Now().UnixMilli()
+	return rvs.update(ctx, review, updateReview)
+}
+
+// SetStatus lets an admin hide or publish a review
+func (rvs *ReviewService) SetStatus(ctx context.Context, review_id string, status *models.ReviewStatus) (*models.Review, *utils.RestError) {
+	review, RestError := rvs.FindById(ctx, review_id)
+	if RestError != nil {
+		return nil, RestError
+	}
+	return rvs.update(ctx, review, bson.M{"status": status.Status, "updated_on": time.Now().UnixMilli()})
+}
+
+// DeleteById deletes a review, users can only delete their own reviews
+func (rvs *ReviewService) DeleteById(ctx context.Context, review_id string, user *models.User) *utils.RestError {
+	review, RestError := rvs.FindById(ctx, review_id)
+	if RestError != nil {
+		return RestError
+	}
+	if review.UserID != user.ID && user.Type != "admin" {
+		return &utils.RestError{Code: http.StatusForbidden, Message: "You can only delete your own review", Error: "forbidden"}
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+	return rvs.transaction(ctx, review.BookID, func(sc mongo.SessionContext) *utils.RestError {
+		result, err := rvs.rvc.DeleteOne(sc, bson.M{"_id": review.ID})
+		if err != nil {
+			return utils.InternalErr("faild to delete.")
+		}
+		if result.DeletedCount == 0 {
+			return utils.NotFound("review not found.")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/hashicorp/go-hclog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SUGGEST_TIMEOUT is the latency budget of the autocomplete endpoint
+const SUGGEST_TIMEOUT = time.Millisecond * 300
+
+var suggestionWeights = map[string]int{"course": 3, "book": 2, "tag": 1}
+
+var srs *SearchService
+
+type SearchService struct {
+	sgc       *mongo.Collection
+	logger    hclog.Logger
+	configs   *utils.Configurations
+	validator *models.Validation
+}
+
+func NewSearchService(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *SearchService {
+	if srs != nil {
+		return srs
+	}
+	srs = &SearchService{models.SuggestionsCollection, logger, configs, validator}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	_, err := srs.sgc.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"keys", 1}, {"weight", -1}}},
+		{Keys: bson.D{{"type", 1}, {"ref_id", 1}}},
+	})
+	if err != nil {
+		logger.Error("unable to create suggestion indexes", "error", err)
+	}
+	return srs
+}
+
+type SuggestQuery struct {
+	Q     string `schema:"q"`
+	Limit int64  `schema:"limit"`
+}
+
+func NewSuggestQuery(q *SuggestQuery) {
+	if q.Limit <= 0 || q.Limit > 20 {
+		q.Limit = 10
+	}
+}
+
+// Index keeps the suggestions of a book or course and its tags in sync with the document
+func (srs *SearchService) Index(ctx context.Context, kind string, id primitive.ObjectID, name string, tags []string, image primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+	srs.index(ctx, kind, id, name, tags, image)
+	// the update may have dropped a tag
+	srs.pruneTags(ctx)
+}
+
+func (srs *SearchService) index(ctx context.Context, kind string, id primitive.ObjectID, name string, tags []string, image primitive.ObjectID) {
+	suggestion := models.NewSuggestion(&models.Suggestion{Type: kind, RefID: id, Text: name, Image: image, Weight: suggestionWeights[kind]})
+	upsert := options.Update().SetUpsert(true)
+	_, err := srs.sgc.UpdateOne(ctx, bson.M{"type": kind, "ref_id": id}, bson.M{"$set": suggestion}, upsert)
+	if err != nil {
+		srs.logger.Error("unable to index suggestion", "type", kind, "id", id.Hex(), "error", err)
+	}
+	for _, tag := range tags {
+		keys := models.SuggestionKeys(tag)
+		if len(keys) == 0 {
+			continue
+		}
+		tagSuggestion := models.NewSuggestion(&models.Suggestion{Type: "tag", Text: tag, Weight: suggestionWeights["tag"]})
+		_, err := srs.sgc.UpdateOne(ctx, bson.M{"type": "tag", "keys": keys[0]}, bson.M{"$setOnInsert": tagSuggestion}, upsert)
+		if err != nil {
+			srs.logger.Error("unable to index tag suggestion", "tag", tag, "error", err)
+		}
+	}
+}
+
+func (srs *SearchService) Remove(ctx context.Context, kind string, id primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+	_, err := srs.sgc.DeleteOne(ctx, bson.M{"type": kind, "ref_id": id})
+	if err != nil {
+		srs.logger.Error("unable to remove suggestion", "type", kind, "id", id.Hex(), "error", err)
+	}
+	srs.pruneTags(ctx)
+}
+
+// pruneTags removes the tag suggestions no book or course carries anymore,
+// they would only lead to empty results
+func (srs *SearchService) pruneTags(ctx context.Context) {
+	keys := bson.A{}
+	for _, collection := range []*mongo.Collection{models.BooksCollection, models.CoursesCollection} {
+		tags, err := collection.Distinct(ctx, "tags", bson.M{})
+		if err != nil {
+			srs.logger.Error("unable to list tags", "collection", collection.Name(), "error", err)
+			return
+		}
+		for _, tag := range tags {
+			if text, ok := tag.(string); ok {
+				if tagKeys := models.SuggestionKeys(text); len(tagKeys) > 0 {
+					keys = append(keys, tagKeys[0])
+				}
+			}
+		}
+	}
+	_, err := srs.sgc.DeleteMany(ctx, bson.M{"type": "tag", "keys.0": bson.M{"$nin": keys}})
+	if err != nil {
+		srs.logger.Error("unable to prune tag suggestions", "error", err)
+	}
+}
+
+func (srs *SearchService) Suggest(ctx context.Context, params *SuggestQuery) (bson.M, *utils.RestError) {
+	q := models.NormalizeSuggestion(params.Q)
+	if q == "" {
+		return bson.M{"docs": []bson.M{}}, nil
+	}
+	matchStage := bson.D{{"$match", bson.M{"keys": bson.M{"$regex": "^" + regexp.QuoteMeta(q)}}}}
+	sortStage := bson.D{{Key: "$sort", Value: bson.D{{"weight", -1}, {"text", 1}}}}
+	limitStage := bson.D{{Key: "$limit", Value: params.Limit}}
+	projectStage := bson.D{{"$project", bson.D{{"keys", 0}, {"weight", 0}, {"updated_on", 0}}}}
+	pipeline := mongo.Pipeline{matchStage, sortStage, limitStage}
+	pipeline = append(pipeline, imageLookupStages(srs.configs.AssetsUrl, "image", "image")...)
+	pipeline = append(pipeline, projectStage)
+
+	ctx, cancel := context.WithTimeout(ctx, SUGGEST_TIMEOUT)
+	defer cancel()
+	cursor, err := srs.sgc.Aggregate(ctx, pipeline)
+	if err == nil {
+		defer cursor.Close(ctx)
+		suggestions := []bson.M{}
+		if err = cursor.All(ctx, &suggestions); err == nil {
+			return bson.M{"docs": suggestions}, nil
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) || mongo.IsTimeout(err) {
+		srs.logger.Warn("suggestions exceeded the latency budget", "q", q)
+		return bson.M{"docs": []bson.M{}}, nil
+	}
+	return nil, utils.InternalErr(err.Error())
+}
+
+// Rebuild recreates the suggestion index from the books and courses collections
+func (srs *SearchService) Rebuild(ctx context.Context) *utils.RestError {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute*5)
+	defer cancel()
+	if _, err := srs.sgc.DeleteMany(ctx, bson.M{}); err != nil {
+		return utils.InternalErr(err.Error())
+	}
+
+	books, err := models.BooksCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+	defer books.Close(ctx)
+	for books.Next(ctx) {
+		var book models.Book
+		if err := books.Decode(&book); err != nil {
+			return utils.InternalErr(err.Error())
+		}
+		srs.index(ctx, "book", book.ID, book.Name, book.Tags, book.Image)
+	}
+
+	courses, err := models.CoursesCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+	defer courses.Close(ctx)
+	for courses.Next(ctx) {
+		var course models.Course
+		if err := courses.Decode(&course); err != nil {
+			return utils.InternalErr(err.Error())
+		}
+		srs.index(ctx, "course", course.ID, course.Name, course.Tags, course.Image)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/hashicorp/go-hclog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const searchLogBatchSize = 100
+
+var sls *SearchLogService
+
+// SearchLogService records the search terms that reach the Find methods.
+// Logs are queued and written in batches so searches never wait on them.
+type SearchLogService struct {
+	slc       *mongo.Collection
+	queue     chan *models.SearchLog
+	logger    hclog.Logger
+	configs   *utils.Configurations
+	validator *models.Validation
+}
+
+func NewSearchLogService(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *SearchLogService {
+	if sls != nil {
+		return sls
+	}
+	sls = &SearchLogService{models.SearchLogsCollection, make(chan *models.SearchLog, 10*searchLogBatchSize), logger, configs, validator}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	_, err := sls.slc.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"created_on", 1}}},
+		{Keys: bson.D{{"term", 1}, {"created_on", 1}}},
+	})
+	if err != nil {
+		logger.Error("unable to create search log indexes", "error", err)
+	}
+	go sls.run()
+	return sls
+}
+
+// Log queues the search of params with the number of results in res. Only the
+// first page is logged so that paging through results does not inflate counts.
+func (sls *SearchLogService) Log(params *GetQuery, scope string, res bson.M) {
+	if params.Search == "" || params.Page != 1 || params.Cursor != "" || params.SkipSearchLog {
+		return
+	}
+	searchLog := models.NewSearchLog(&models.SearchLog{Query: params.Search, Scope: scope, Results: resultCount(res), UserID: params.UserID})
+	select {
+	case sls.queue <- searchLog:
+	default:
+		sls.logger.Warn("search log queue is full, dropping", "query", params.Search)
+	}
+}
+
+func (sls *SearchLogService) run() {
+	ticker := time.NewTicker(time.Second * 5)
+	defer ticker.Stop()
+	batch := []interface{}{}
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		defer cancel()
+		if _, err := sls.slc.InsertMany(ctx, batch); err != nil {
+			sls.logger.Error("unable to write search logs", "error", err)
+		}
+		batch = []interface{}{}
+	}
+	for {
+		select {
+		case searchLog := <-sls.queue:
+			batch = append(batch, searchLog)
+			if len(batch) >= searchLogBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+type AnalyticsQuery struct {
+	From     int64  `schema:"from"` // unix millis
+	To       int64  `schema:"to"`   // unix millis
+	Scope    string `schema:"scope"`
+	Q        string `schema:"q"`
+	Interval string `schema:"interval"` // day,week,month
+	Limit    int64  `schema:"limit"`
+}
+
+func NewAnalyticsQuery(q *AnalyticsQuery) {
+	if q.To == 0 {
+		q.To = time.Now().UnixMilli()
+	}
+	if q.From == 0 {
+		q.From = q.To - (30 * 24 * time.Hour).Milliseconds()
+	}
+	if q.Limit <= 0 || q.Limit > MAX_LIMIT {
+		q.Limit = 20
+	}
+	if q.Interval == "" {
+		q.Interval = "day"
+	}
+}
+
+func (sls *SearchLogService) match(params *AnalyticsQuery) bson.M {
+	query := bson.M{"created_on": bson.M{"$gte": params.From, "$lte": params.To}}
+	if params.Scope != "" {
+		query["scope"] = params.Scope
+	}
+	if params.Q != "" {
+		query["term"] = models.NewSearchLog(&models.SearchLog{Query: params.Q}).Term
+	}
+	return query
+}
+
+func (sls *SearchLogService) aggregate(ctx context.Context, pipeline mongo.Pipeline) ([]bson.M, *utils.RestError) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+	cursor, err := sls.slc.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	docs := []bson.M{}
+	if err = cursor.All(ctx, &docs); err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	return docs, nil
+}
+
+// TopQueries returns the most searched terms in the date range
+func (sls *SearchLogService) TopQueries(ctx context.Context, params *AnalyticsQuery) ([]bson.M, *utils.RestError) {
+	matchStage := bson.D{{"$match", sls.match(params)}}
+	groupStage := bson.D{{
+		"$group", bson.D{
+			{"_id", "$term"},
+			{"count", bson.D{{"$sum", 1}}},
+			{"zero_results", bson.D{{"$sum", bson.D{{"$cond", bson.A{bson.D{{"$eq", bson.A{"$results", 0}}}, 1, 0}}}}}},
+			{"avg_results", bson.D{{"$avg", "$results"}}},
+			{"users", bson.D{{"$addToSet", "$user_id"}}},
+			{"last_searched_on", bson.D{{"$max", "$created_on"}}},
+		},
+	}}
+	projectStage := bson.D{{"$project", bson.D{
+		{"_id", 0},
+		{"query", "$_id"},
+		{"count", 1},
+		{"zero_results", 1},
+		{"avg_results", 1},
+		{"users", bson.D{{"$size", "$users"}}},
+		{"last_searched_on", 1},
+	}}}
+	sortStage := bson.D{{"$sort", bson.D{{"count", -1}, {"query", 1}}}}
+	limitStage := bson.D{{"$limit", params.Limit}}
+	return sls.aggregate(ctx, mongo.Pipeline{matchStage, groupStage, projectStage, sortStage, limitStage})
+}
+
+// ZeroResultQueries returns the terms that found nothing, most frequent first
+func (sls *SearchLogService) ZeroResultQueries(ctx context.Context, params *AnalyticsQuery) ([]bson.M, *utils.RestError) {
+	query := sls.match(params)
+	query["results"] = 0
+	matchStage := bson.D{{"$match", query}}
+	groupStage := bson.D{{
+		"$group", bson.D{
+			{"_id", "$term"},
+			{"count", bson.D{{"$sum", 1}}},
+			{"scopes", bson.D{{"$addToSet", "$scope"}}},
+			{"last_searched_on", bson.D{{"$max", "$created_on"}}},
+		},
+	}}
+	projectStage := bson.D{{"$project", bson.D{{"_id", 0}, {"query", "$_id"}, {"count", 1}, {"scopes", 1}, {"last_searched_on", 1}}}}
+	sortStage := bson.D{{"$sort", bson.D{{"count", -1}, {"query", 1}}}}
+	limitStage := bson.D{{"$limit", params.Limit}}
+	return sls.aggregate(ctx, mongo.Pipeline{matchStage, groupStage, projectStage, sortStage, limitStage})
+}
+
+// Trends returns the number of searches and zero result searches per interval
+func (sls *SearchLogService) Trends(ctx context.Context, params *AnalyticsQuery) ([]bson.M, *utils.RestError) {
+	var format string
+	switch params.Interval {
+	case "day":
+		format = "%Y-%m-%d"
+	case "week":
+		format = "%G-W%V"
+	case "month":
+		format = "%Y-%m"
+	default:
+		return nil, utils.BadRequest("interval should be one of day, week or month")
+	}
+	matchStage := bson.D{{"$match", sls.match(params)}}
+	groupStage := bson.D{{
+		"$group", bson.D{
+			{"_id", bson.D{{"$dateToString", bson.D{{"format", format}, {"date", bson.D{{"$toDate", "$created_on"}}}}}}},
+			{"count", bson.D{{"$sum", 1}}},
+			{"zero_results", bson.D{{"$sum", bson.D{{"$cond", bson.A{bson.D{{"$eq", bson.A{"$results", 0}}}, 1, 0}}}}}},
+			{"terms", bson.D{{"$addToSet", "$term"}}},
+		},
+	}}
+	projectStage := bson.D{{"$project", bson.D{{"_id", 0}, {"period", "$_id"}, {"count", 1}, {"zero_results", 1}, {"unique_queries", bson.D{{"$size", "$terms"}}}}}}
+	sortStage := bson.D{{"$sort", bson.D{{"period", 1}}}}
+	return sls.aggregate(ctx, mongo.Pipeline{matchStage, groupStage, projectStage, sortStage})
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// kinds of the query sections
+const (
+	QUERY_NEW_ARRIVALS    = "new_arrivals"
+	QUERY_MOST_DISCOUNTED = "most_discounted"
+	QUERY_BEST_SELLERS    = "best_sellers"
+	QUERY_COURSE          = "course"
+	QUERY_TAG             = "tag"
+)
+
+const (
+	DEFAULT_QUERY_LIMIT = 10
+	MAX_QUERY_LIMIT     = 50
+)
+
+// BOOKS_TAG is carried by the cached feeds with query sections, any change to
+// a book or its stocks can change what they render
+const BOOKS_TAG = "books"
+
+// since returns the unix millis days ago, or 0 for all time
+func since(days int) int64 {
+	if days <= 0 {
+		return 0
+	}
+	return time.Now().Add(-time.Duration(days) * 24 * time.Hour).UnixMilli()
+}
+
+// rankedIDs runs a pipeline that groups books into _id and returns the ids in order
+func rankedIDs(ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline) ([]primitive.ObjectID, error) {
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	ids := []primitive.ObjectID{}
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		ids = append(ids, doc.ID)
+	}
+	return ids, cursor.Err()
+}
+
+// queryBookIDs returns the ids of the books matched by query, in order
+func queryBookIDs(ctx context.Context, query *models.SectionQuery) ([]primitive.ObjectID, error) {
+	limit := int64(query.Limit)
+	if limit <= 0 {
+		limit = DEFAULT_QUERY_LIMIT
+	}
+	switch query.Kind {
+	case QUERY_MOST_DISCOUNTED:
+		return rankedIDs(ctx, models.StocksCollection, mongo.Pipeline{
+			bson.D{{"$match", bson.M{"status": "available", "discount_percent": bson.M{"$gt": 0}}}},
+			bson.D{{"$group", bson.D{{"_id", "$book_id"}, {"discount_percent", bson.D{{"$max", "$discount_percent"}}}}}},
+			bson.D{{"$sort", bson.D{{"discount_percent", -1}, {"_id", 1}}}},
+			bson.D{{"$limit", limit}},
+		})
+	case QUERY_BEST_SELLERS:
+		match := bson.M{"status": models.PaymentStatusPaid}
+		if from := since(query.Days); from != 0 {
+			match["created_on"] = bson.M{"$gte": from}
+		}
+		return rankedIDs(ctx, models.OrdersCollection, mongo.Pipeline{
+			bson.D{{"$match", match}},
+			bson.D{{"$unwind", "$items"}},
+			bson.D{{"$group", bson.D{{"_id", "$items.book_id"}, {"count", bson.D{{"$sum", 1}}}}}},
+			bson.D{{"$sort", bson.D{{"count", -1}, {"_id", 1}}}},
+			bson.D{{"$limit", limit}},
+		})
+	}
+
+	filter := bson.M{}
+	sort := bson.D{{"order", 1}, {"_id", 1}}
+	switch query.Kind {
+	case QUERY_NEW_ARRIVALS:
+		if from := since(query.Days); from != 0 {
+			filter["created_on"] = bson.M{"$gte": from}
+		}
+		sort = bson.D{{"created_on", -1}, {"_id", -1}}
+	case QUERY_COURSE:
+		filter["course_id"] = query.Course
+	case QUERY_TAG:
+		filter["tags"] = query.Tag
+	default:
+		return []primitive.ObjectID{}, nil
+	}
+	opts := options.Find().SetSort(sort).SetLimit(limit).SetProjection(bson.M{"_id": 1})
+	cursor, err := models.BooksCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var books []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err = cursor.All(ctx, &books); err != nil {
+		return nil, err
+	}
+	ids := []primitive.ObjectID{}
+	for _, book := range books {
+		ids = append(ids, book.ID)
+	}
+	return ids, nil
+}
+
+// evaluateQueries fills the books of every query section in the feeds of res
+func (fs *FeedService) evaluateQueries(ctx context.Context, res bson.M) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	evaluated := map[models.SectionQuery][]bson.M{}
+
+	var walk func(sections interface{}) error
+	walk = func(sections interface{}) error {
+		list, _ := sections.(bson.A)
+		for _, item := range list {
+			section, ok := item.(bson.M)
+			if !ok {
+				continue
+			}
+			if opts, ok := section["options"].(bson.M); ok {
+				if err := walk(opts["sections"]); err != nil {
+					return err
+				}
+			}
+			if section["type"] != "query" || section["query"] == nil {
+				continue
+			}
+			raw, err := bson.Marshal(section["query"])
+			if err != nil {
+				return err
+			}
+			var query models.SectionQuery
+			if err := bson.Unmarshal(raw, &query); err != nil {
+				return err
+			}
+			books, ok := evaluated[query]
+			if !ok {
+				ids, err := queryBookIDs(ctx, &query)
+				if err != nil {
+					return err
+				}
+				if books, err = hydrateBooks(ctx, fs.configs.AssetsUrl, ids); err != nil {
+					return err
+				}
+				evaluated[query] = books
+			}
+			section["books"] = books
+		}
+		return nil
+	}
+
+	var docs bson.A
+	switch list := res["docs"].(type) {
+	case bson.A:
+		docs = list
+	case []bson.M:
+		for _, doc := range list {
+			docs = append(docs, doc)
+		}
+	}
+	for _, doc := range docs {
+		if feed, ok := doc.(bson.M); ok {
+			if err := walk(feed["sections"]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
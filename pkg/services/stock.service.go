@@ -20,10 +20,12 @@ type StockService struct {
 	logger    hclog.Logger
 	configs   *utils.Configurations
 	validator *models.Validation
+	searchLog *SearchLogService
+	cache     utils.Cache
 }
 
 func NewStockService(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *StockService {
-	return &StockService{models.StocksCollection, logger, configs, validator}
+	return &StockService{models.StocksCollection, logger, configs, validator, NewSearchLogService(logger, configs, validator), NewFeedCache(logger, configs)}
 }
 
 func (ss *StockService) Create(ctx context.Context, stock *models.Stock) (*models.Stock, *utils.RestError) {
@@ -36,11 +38,12 @@ func (ss *StockService) Create(ctx context.Context, stock *models.Stock) (*model
 		return nil, RestError
 	}
 	stock.ID = result.InsertedID.(primitive.ObjectID)
+	// the stock summary of the book is rendered in the feeds
+	ss.cache.Invalidate(ctx, BOOKS_TAG, BookTag(stock.BookID))
 	return stock, nil
 }
 
 func (ss *StockService) Find(ctx context.Context, params *GetQuery) (bson.M, *utils.RestError) {
-	skip := (params.Page - 1) * params.Limit
 	query := bson.M{}
 	// opts := options.Find().SetSkip(skip).SetLimit(params.Limit)
 	if params.ID != "" {
@@ -62,7 +65,6 @@ func (ss *StockService) Find(ctx context.Context, params *GetQuery) (bson.M, *ut
 		}
 	}
 
-	matchStage := bson.D{{"$match", query}}
 	bookPipelineStage := bson.D{
 		{"$lookup", bson.D{
 			{"from", "books"},
@@ -111,19 +113,11 @@ func (ss *StockService) Find(ctx context.Context, params *GetQuery) (bson.M, *ut
 	}
 	courseUnwindStage := bson.D{{"$unwind", bson.D{{"path", "$course"}, {"preserveNullAndEmptyArrays", true}}}}
 
-	sortStage := bson.D{{Key: "$sort", Value: bson.D{{"order", 1}}}}
-	skipStage := bson.D{{Key: "$skip", Value: skip}}
-	limitStage := bson.D{{Key: "$limit", Value: params.Limit}}
-	countStage := bson.D{{Key: "$count", Value: "count"}}
-
-	facetStage := bson.D{{
-		"$facet", bson.D{
-			{"docs", bson.A{sortStage, skipStage, limitStage}},
-			{"total", bson.A{countStage}},
-		},
-	}}
-	unwindStage2 := bson.D{{"$unwind", "$total"}}
-	pipeline := mongo.Pipeline{matchStage, bookPipelineStage, bookUnwindStage, coursePipelineStage, courseUnwindStage, facetStage, unwindStage2}
+	sortBy := SortBy{"order", 1}
+	pipeline, after, RestError := Paginate(params, query, mongo.Pipeline{bookPipelineStage, bookUnwindStage, coursePipelineStage, courseUnwindStage}, sortBy)
+	if RestError != nil {
+		return nil, RestError
+	}
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
 	defer cancel()
@@ -141,10 +135,9 @@ func (ss *StockService) Find(ctx context.Context, params *GetQuery) (bson.M, *ut
 		RestError := utils.InternalErr(err.Error())
 		return nil, RestError
 	}
-	if len(stocks) == 0 {
-		return bson.M{"docs": []bson.M{}, "total": bson.M{"count": 0}}, nil
-	}
-	return stocks[0], nil
+	res := PageResult(params, stocks, sortBy, after)
+	ss.searchLog.Log(params, "stocks", res)
+	return res, nil
 }
 func (ss *StockService) FindById(ctx context.Context, stock_id string) (*models.Stock, *utils.RestError) {
 	var stock models.Stock
@@ -171,7 +164,11 @@ func (ss *StockService) DeleteById(ctx context.Context, stock_id string) *utils.
 		RestError := utils.NotFound("Invalid user_id")
 		return RestError
 	}
-	result, err := cs.cc.DeleteOne(ctx, bson.M{"_id": id})
+	stock, RestError := ss.FindById(ctx, stock_id)
+	if RestError != nil {
+		return RestError
+	}
+	result, err := ss.sc.DeleteOne(ctx, bson.M{"_id": id})
 	if err != nil {
 		RestError := utils.NotFound("faild to delete.")
 		return RestError
@@ -180,6 +177,7 @@ func (ss *StockService) DeleteById(ctx context.Context, stock_id string) *utils.
 		RestError := utils.NotFound("stock not found.")
 		return RestError
 	}
+	ss.cache.Invalidate(ctx, BOOKS_TAG, BookTag(stock.BookID))
 	return nil
 }
 
@@ -202,9 +200,11 @@ func (ss *StockService) UpdateById(ctx context.Context, stock_id string, updateB
 		return nil, utils.InternalErr(result.Err().Error())
 	}
 	// Decode the result
+	bookID := stock.BookID
 	decodeErr := result.Decode(stock)
 	if decodeErr != nil {
 		return nil, utils.InternalErr(decodeErr.Error())
 	}
+	ss.cache.Invalidate(ctx, BOOKS_TAG, BookTag(bookID), BookTag(stock.BookID))
 	return stock, nil
 }
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"github.com/hashicorp/go-hclog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TUS_VERSION is the version of the tus resumable upload protocol served
+const TUS_VERSION = "1.0.0"
+
+// UPLOADS_PREFIX is where the chunks of the unfinished uploads are kept
+const UPLOADS_PREFIX = "uploads/"
+
+var ups *UploadService
+
+type UploadService struct {
+	uc           *mongo.Collection
+	logger       hclog.Logger
+	configs      *utils.Configurations
+	validator    *models.Validation
+	store        utils.BlobStore
+	mediaService *MediaService
+}
+
+func NewUploadService(logger hclog.Logger, configs *utils.Configurations, validator *models.Validation) *UploadService {
+	if ups != nil {
+		return ups
+	}
+	ups = &UploadService{models.UploadsCollection, logger, configs, validator, NewMediaStore(logger, configs), NewMediaService(logger, configs, validator)}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	_, err := ups.uc.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{"expires_at", 1}}, Options: options.Index().SetExpireAfterSeconds(0)})
+	if err != nil {
+		logger.Error("unable to create upload indexes", "error", err)
+	}
+	return ups
+}
+
+// ParseUploadMetadata reads the Upload-Metadata header, comma separated pairs
+// of a key and its base64 encoded value
+func ParseUploadMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, " ", 2)
+		value := []byte{}
+		if len(parts) == 2 {
+			decoded, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("metadata %s should be base64 encoded", parts[0])
+			}
+			value = decoded
+		}
+		metadata[parts[0]] = string(value)
+	}
+	return metadata, nil
+}
+
+func (ups *UploadService) expiresAt() time.Time {
+	return time.Now().Add(time.Hour * time.Duration(ups.configs.UploadExpiration))
+}
+
+func (ups *UploadService) Create(ctx context.Context, length int64, metadata map[string]string, user_id primitive.ObjectID) (*models.Upload, *utils.RestError) {
+	if length <= 0 {
+		return nil, utils.BadRequest("Upload-Length should be a positive number")
+	}
+	if length > ups.configs.UploadMaxSize {
+		return nil, &utils.RestError{Code: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("uploads should be atmost %d bytes", ups.configs.UploadMaxSize), Error: "request entity too large"}
+	}
+	filename := metadata["filename"]
+	if filename == "" {
+		filename = "upload"
+	}
+	upload := models.NewUpload(&models.Upload{Length: length, Filename: filename, Metadata: metadata, CreatedBy: user_id, ExpiresAt: ups.expiresAt()})
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	result, err := ups.uc.InsertOne(ctx, upload)
+	if err != nil {
+		return nil, utils.InternalErr("can't insert upload to the database.")
+	}
+	upload.ID = result.InsertedID.(primitive.ObjectID)
+	return upload, nil
+}
+
+// FindById returns an upload of the user
+func (ups *UploadService) FindById(ctx context.Context, upload_id string, user_id primitive.ObjectID) (*models.Upload, *utils.RestError) {
+	id, e := primitive.ObjectIDFromHex(upload_id)
+	if e != nil {
+		return nil, utils.NotFound("Invalid upload_id")
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	var upload models.Upload
+	err := ups.uc.FindOne(ctx, bson.M{"_id": id, "created_by": user_id}).Decode(&upload)
+	if err != nil {
+		return nil, utils.NotFound("upload not found.")
+	}
+	return &upload, nil
+}
+
+// WriteChunk appends body to the upload at offset, which has to be where the
+// upload currently ends. The last chunk turns the upload into a media.
+func (ups *UploadService) WriteChunk(ctx context.Context, upload_id string, offset int64, body io.Reader, user_id primitive.ObjectID) (*models.Upload, *utils.RestError) {
+	upload, RestError := ups.FindById(ctx, upload_id, user_id)
+	if RestError != nil {
+		return nil, RestError
+	}
+	if upload.Status != models.UploadStatusPending || offset != upload.Offset {
+		return nil, &utils.RestError{Code: http.StatusConflict, Message: fmt.Sprintf("Upload-Offset should be %d", upload.Offset), Error: "conflict"}
+	}
+
+	// keep what arrived even when the connection drops, the client resumes
+	// from there. The chunk is spooled to disk, a chunk can be the whole file.
+	remaining := upload.Length - upload.Offset
+	chunk, err := os.CreateTemp("", "upload-chunk-")
+	if err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	defer os.Remove(chunk.Name())
+	defer chunk.Close()
+	size, err := io.Copy(chunk, io.LimitReader(body, remaining+1))
+	if size > remaining {
+		return nil, &utils.RestError{Code: http.StatusRequestEntityTooLarge, Message: "the chunk goes past Upload-Length", Error: "request entity too large"}
+	}
+	if size == 0 {
+		if err != nil {
+			return nil, utils.BadRequest(err.Error())
+		}
+		return upload, nil
+	}
+	if _, err := chunk.Seek(0, io.SeekStart); err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+
+	// a retried PATCH can run next to the one it retries, every write gets its
+	// own key so the request losing the race only deletes its own chunk
+	key := fmt.Sprintf("%s%s/%d-%s", UPLOADS_PREFIX, upload.ID.Hex(), offset, primitive.NewObjectID().Hex())
+	if err := ups.store.Put(ctx, key, chunk, size, "application/offset+octet-stream"); err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	update := bson.M{
+		"$set":  bson.M{"offset": offset + size, "updated_on": time.Now().UnixMilli(), "expires_at": ups.expiresAt()},
+		"$push": bson.M{"chunks": models.UploadChunk{Offset: offset, Size: size, Key: key}},
+	}
+	after := options.After
+	opts := options.FindOneAndUpdateOptions{ReturnDocument: &after}
+	filter := bson.M{"_id": upload.ID, "offset": offset, "status": models.UploadStatusPending}
+	if err := ups.uc.FindOneAndUpdate(ctx, filter, update, &opts).Decode(upload); err != nil {
+		// another request wrote this offset first
+		ups.store.Delete(ctx, key)
+		return nil, &utils.RestError{Code: http.StatusConflict, Message: "the upload was changed by another request", Error: "conflict"}
+	}
+	if upload.Offset == upload.Length {
+		return ups.complete(ctx, upload)
+	}
+	return upload, nil
+}
+
+// complete joins the chunks and creates the media the same way the form
+// uploads do. A rejected file fails the upload, it can not be resumed.
+func (ups *UploadService) complete(ctx context.Context, upload *models.Upload) (*models.Upload, *utils.RestError) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	defer ups.deleteChunks(ctx, upload.Chunks)
+
+	media, RestError := ups.createMedia(ctx, upload)
+	set := bson.M{"status": models.UploadStatusComplete, "updated_on": time.Now().UnixMilli()}
+	if RestError != nil {
+		set["status"], set["error"] = models.UploadStatusFailed, RestError.Message
+	} else {
+		set["media_id"] = media.ID
+	}
+	_, err := ups.uc.UpdateOne(ctx, bson.M{"_id": upload.ID}, bson.M{"$set": set, "$unset": bson.M{"chunks": ""}})
+	if err != nil {
+		ups.logger.Error("unable to finish upload", "upload", upload.ID.Hex(), "error", err)
+	}
+	if RestError != nil {
+		return nil, RestError
+	}
+	upload.Status, upload.MediaID, upload.Chunks = models.UploadStatusComplete, media.ID, nil
+	return upload, nil
+}
+
+func (ups *UploadService) createMedia(ctx context.Context, upload *models.Upload) (*models.Media, *utils.RestError) {
+	file, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	for _, chunk := range upload.Chunks {
+		blob, _, err := ups.store.Get(ctx, chunk.Key)
+		if err != nil {
+			return nil, utils.InternalErr(err.Error())
+		}
+		_, err = io.Copy(file, blob)
+		blob.Close()
+		if err != nil {
+			return nil, utils.InternalErr(err.Error())
+		}
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, utils.InternalErr(err.Error())
+	}
+
+	private := upload.Metadata["private"] == "true"
+	dir, seen := utils.ASSETS_DIR, utils.HashLookup(func(hash string) bool { return ups.mediaService.HasHash(ctx, hash) })
+	if private {
+		dir, seen = utils.PRIVATE_DIR, nil
+	}
+	uploaded, err := utils.SaveUpload(ctx, ups.store, dir, file, seen)
+	if err != nil {
+		return nil, &utils.RestError{Code: http.StatusUnsupportedMediaType, Message: err.Error(), Error: "unsupported media type"}
+	}
+	return ups.mediaService.CreateFromUpload(ctx, uploaded, upload.Metadata["alt"], private, upload.CreatedBy)
+}
+
+func (ups *UploadService) deleteChunks(ctx context.Context, chunks []models.UploadChunk) {
+	for _, chunk := range chunks {
+		if err := ups.store.Delete(ctx, chunk.Key); err != nil && err != utils.ErrBlobNotFound {
+			ups.logger.Error("unable to delete upload chunk", "key", chunk.Key, "error", err)
+		}
+	}
+}
+
+// Terminate drops an upload and the chunks received so far
+func (ups *UploadService) Terminate(ctx context.Context, upload_id string, user_id primitive.ObjectID) *utils.RestError {
+	upload, RestError := ups.FindById(ctx, upload_id, user_id)
+	if RestError != nil {
+		return RestError
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+	if _, err := ups.uc.DeleteOne(ctx, bson.M{"_id": upload.ID}); err != nil {
+		return utils.InternalErr("faild to delete.")
+	}
+	ups.deleteChunks(ctx, upload.Chunks)
+	return nil
+}
@@ -94,6 +94,19 @@ func (us *UserService) FindById(ctx context.Context, user_id string) (*models.Us
 	return &user, nil
 }
 
+// FindByEmail returns the user registered with email
+func (us *UserService) FindByEmail(ctx context.Context, email string) (*models.User, *utils.RestError) {
+	var user models.User
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	err := us.uc.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	if err != nil {
+		RestError := utils.NotFound("user not found.")
+		return nil, RestError
+	}
+	return &user, nil
+}
+
 func (us *UserService) DeleteById(ctx context.Context, user_id string) *utils.RestError {
 	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
 	defer cancel()
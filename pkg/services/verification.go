@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/SairamVemula/booksland-backend-go/pkg/models"
+	"github.com/SairamVemula/booksland-backend-go/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// limits of the email verification, a code allows VERIFICATION_MAX_ATTEMPTS
+// wrong guesses and a user gets VERIFICATION_MAX_SENDS codes until it expires
+const (
+	VERIFICATION_MAX_ATTEMPTS    = 5
+	VERIFICATION_MAX_SENDS       = 5
+	VERIFICATION_RESEND_INTERVAL = time.Minute
+)
+
+func invalidCode() *utils.RestError {
+	return utils.BadRequest("the code is invalid or expired")
+}
+
+func tooManyRequests(message string) *utils.RestError {
+	return &utils.RestError{Code: http.StatusTooManyRequests, Message: message, Error: "too many requests"}
+}
+
+// generateCode returns a random 6 digit code
+func generateCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// SendVerification mails a new verification code to the user, replacing the
+// one sent before
+func (ms *Mail) SendVerification(ctx context.Context, user *models.User) *utils.RestError {
+	code, err := generateCode()
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), 10)
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	now := time.Now()
+	// a verification still waiting out the interval or out of sends does not
+	// match, so the upsert runs into the unique user_id and nothing is sent
+	filter := bson.M{
+		"user_id": user.ID,
+		"sent_on": bson.M{"$lte": now.Add(-VERIFICATION_RESEND_INTERVAL).UnixMilli()},
+		"sends":   bson.M{"$lt": VERIFICATION_MAX_SENDS},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"email":      user.Email,
+			"code_hash":  string(hash),
+			"attempts":   0,
+			"sent_on":    now.UnixMilli(),
+			"expires_at": now.Add(time.Hour * time.Duration(ms.configs.MailVerifCodeExpiration)),
+			"updated_on": now.UnixMilli(),
+		},
+		"$inc":         bson.M{"sends": 1},
+		"$setOnInsert": bson.M{"created_on": now.UnixMilli()},
+	}
+	_, err = ms.vc.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return tooManyRequests("a code was sent recently, please wait before asking for another")
+	}
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+	if err := ms.sendMail(user, code); err != nil {
+		ms.logger.Error("unable to send verification mail", "user", user.ID.Hex(), "error", err)
+		return utils.InternalErr("unable to send the verification mail")
+	}
+	return nil
+}
+
+// ResendVerification sends a new code to email. Unknown and verified emails
+// get the same answer, and so do throttled and failed sends, so the endpoint
+// does not tell who is registered. Failures are only logged.
+func (ms *Mail) ResendVerification(ctx context.Context, email string) {
+	user, RestError := ms.us.FindByEmail(ctx, email)
+	if RestError != nil || user.Verified.Email {
+		return
+	}
+	if RestError := ms.SendVerification(ctx, user); RestError != nil {
+		ms.logger.Warn("unable to resend verification", "user", user.ID.Hex(), "error", RestError.Message)
+	}
+}
+
+// VerifyEmail checks the code sent to email and marks the email verified
+func (ms *Mail) VerifyEmail(ctx context.Context, email string, code string) *utils.RestError {
+	user, RestError := ms.us.FindByEmail(ctx, email)
+	if RestError != nil {
+		return invalidCode()
+	}
+	if user.Verified.Email {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+
+	// the attempt is counted before the code is compared. The TTL index only
+	// drops expired codes about every minute, so the expiry is checked here.
+	var verification models.Verification
+	now := time.Now()
+	live := bson.M{"user_id": user.ID, "email": user.Email, "expires_at": bson.M{"$gt": now}}
+	filter := bson.M{"user_id": user.ID, "email": user.Email, "expires_at": bson.M{"$gt": now}, "attempts": bson.M{"$lt": VERIFICATION_MAX_ATTEMPTS}}
+	err := ms.vc.FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"attempts": 1}}).Decode(&verification)
+	if err == mongo.ErrNoDocuments {
+		count, _ := ms.vc.CountDocuments(ctx, live)
+		if count > 0 {
+			return tooManyRequests("too many wrong codes, please ask for a new one")
+		}
+		return invalidCode()
+	}
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+	if bcrypt.CompareHashAndPassword([]byte(verification.CodeHash), []byte(code)) != nil {
+		return invalidCode()
+	}
+
+	result, err := models.UsersCollection.UpdateOne(ctx, bson.M{"_id": user.ID, "email": verification.Email}, bson.M{"$set": bson.M{"verified.email": true, "updated_on": time.Now().UnixMilli()}})
+	if err != nil {
+		return utils.InternalErr(err.Error())
+	}
+	if result.MatchedCount == 0 {
+		return invalidCode()
+	}
+	if _, err := ms.vc.DeleteOne(ctx, bson.M{"_id": verification.ID}); err != nil {
+		ms.logger.Error("unable to delete verification", "user", user.ID.Hex(), "error", err)
+	}
+	return nil
+}
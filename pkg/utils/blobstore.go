@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+type BlobInfo struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type,omitempty"`
+	ModTime     time.Time `json:"mod_time"`
+}
+
+// BlobStore keeps the uploaded media. Keys are slash separated paths such as
+// assets/cover-1.png, the media path is the key with a leading slash.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadSeekCloser, *BlobInfo, error)
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (*BlobInfo, error)
+	// List calls fn with every blob whose key starts with prefix
+	List(ctx context.Context, prefix string, fn func(*BlobInfo) error) error
+	URL(key string) string
+}
+
+// BlobKey turns a media path into its key
+func BlobKey(path string) string {
+	return strings.TrimPrefix(path, "/")
+}
+
+// NewBlobStore returns the store selected by BLOB_DRIVER, local or s3
+func NewBlobStore(logger hclog.Logger, configs *Configurations) (BlobStore, error) {
+	switch configs.BlobDriver {
+	case "", "local":
+		return NewLocalBlobStore(configs.BlobLocalDir, configs.AssetsUrl), nil
+	case "s3":
+		return NewS3BlobStore(logger, configs)
+	}
+	return nil, fmt.Errorf("unknown blob driver %s", configs.BlobDriver)
+}
+
+// LocalBlobStore keeps the blobs under a directory of the local disk
+type LocalBlobStore struct {
+	root    string
+	baseUrl string
+}
+
+func NewLocalBlobStore(root string, baseUrl string) *LocalBlobStore {
+	if root == "" {
+		root = "."
+	}
+	return &LocalBlobStore{root, baseUrl}
+}
+
+// path resolves key inside root, keys can not climb out of it
+func (s *LocalBlobStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" {
+		return "", fmt.Errorf("invalid key %s", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
+
+func (s *LocalBlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	// write to a temporary file first so readers never see half a blob
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalBlobStore) Get(ctx context.Context, key string) (io.ReadSeekCloser, *BlobInfo, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return file, s.info(key, stat), nil
+}
+
+func (s *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrBlobNotFound
+	}
+	return err
+}
+
+func (s *LocalBlobStore) Stat(ctx context.Context, key string) (*BlobInfo, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.info(key, stat), nil
+}
+
+func (s *LocalBlobStore) List(ctx context.Context, prefix string, fn func(*BlobInfo) error) error {
+	root := filepath.Clean(s.root)
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if entry.IsDir() {
+			// skip the directories that can not hold a key with prefix
+			if key != "." && !strings.HasPrefix(key+"/", prefix) && !strings.HasPrefix(prefix, key+"/") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasPrefix(key, prefix) || strings.HasPrefix(entry.Name(), ".upload-") {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		stat, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return fn(s.info(key, stat))
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *LocalBlobStore) URL(key string) string {
+	return s.baseUrl + "/" + BlobKey(key)
+}
+
+func (s *LocalBlobStore) info(key string, stat os.FileInfo) *BlobInfo {
+	return &BlobInfo{Key: key, Size: stat.Size(), ContentType: mime.TypeByExtension(filepath.Ext(key)), ModTime: stat.ModTime()}
+}
@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3BlobStore keeps the blobs in a bucket of an S3 compatible API such as
+// MinIO. ASSETS_URL should point at the bucket so the media urls resolve.
+// Private media and upload chunks go to S3_PRIVATE_BUCKET, which should never
+// be readable without credentials.
+type S3BlobStore struct {
+	client        *minio.Client
+	bucket        string
+	privateBucket string
+	baseUrl       string
+}
+
+// privatePrefixes are the keys kept in the private bucket
+var privatePrefixes = []string{"private/", "uploads/"}
+
+func NewS3BlobStore(logger hclog.Logger, configs *Configurations) (*S3BlobStore, error) {
+	client, err := minio.New(configs.S3Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(configs.S3AccessKey, configs.S3SecretKey, ""),
+		Secure: configs.S3UseSSL,
+		Region: configs.S3Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if configs.S3PrivateBucket == "" || configs.S3PrivateBucket == configs.S3Bucket {
+		return nil, errors.New("S3_PRIVATE_BUCKET should be set and differ from S3_BUCKET")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	for _, bucket := range []string{configs.S3Bucket, configs.S3PrivateBucket} {
+		exists, err := client.BucketExists(ctx, bucket)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			logger.Info("creating the media bucket", "bucket", bucket)
+			if err := client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{Region: configs.S3Region}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &S3BlobStore{client, configs.S3Bucket, configs.S3PrivateBucket, configs.AssetsUrl}, nil
+}
+
+// bucketOf returns the bucket a key or a prefix is kept in
+func (s *S3BlobStore) bucketOf(key string) string {
+	key = BlobKey(key)
+	for _, prefix := range privatePrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return s.privateBucket
+		}
+	}
+	return s.bucket
+}
+
+// notFound maps the missing object errors of the API to ErrBlobNotFound
+func notFound(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return ErrBlobNotFound
+	}
+	return err
+}
+
+func (s *S3BlobStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucketOf(key), BlobKey(key), r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3BlobStore) Get(ctx context.Context, key string) (io.ReadSeekCloser, *BlobInfo, error) {
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	object, err := s.client.GetObject(ctx, s.bucketOf(key), BlobKey(key), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, notFound(err)
+	}
+	return object, info, nil
+}
+
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	// removing a missing object succeeds on S3, stat first to report it
+	if _, err := s.Stat(ctx, key); err != nil {
+		return err
+	}
+	return s.client.RemoveObject(ctx, s.bucketOf(key), BlobKey(key), minio.RemoveObjectOptions{})
+}
+
+func (s *S3BlobStore) Stat(ctx context.Context, key string) (*BlobInfo, error) {
+	object, err := s.client.StatObject(ctx, s.bucketOf(key), BlobKey(key), minio.StatObjectOptions{})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &BlobInfo{Key: key, Size: object.Size, ContentType: object.ContentType, ModTime: object.LastModified}, nil
+}
+
+func (s *S3BlobStore) List(ctx context.Context, prefix string, fn func(*BlobInfo) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for object := range s.client.ListObjects(ctx, s.bucketOf(prefix), minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return object.Err
+		}
+		if err := fn(&BlobInfo{Key: object.Key, Size: object.Size, ContentType: object.ContentType, ModTime: object.LastModified}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *S3BlobStore) URL(key string) string {
+	return s.baseUrl + "/" + BlobKey(key)
+}
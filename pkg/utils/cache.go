@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// Cache stores rendered responses. Every entry carries tags naming the
+// documents it was rendered from, so a change to one of them can drop
+// exactly the entries that used it.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string)
+	Invalidate(ctx context.Context, tags ...string)
+	Stats() CacheStats
+}
+
+type CacheStats struct {
+	Driver        string  `json:"driver"`
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	HitRatio      float64 `json:"hit_ratio"`
+	Sets          int64   `json:"sets"`
+	Invalidations int64   `json:"invalidations"`
+	Evictions     int64   `json:"evictions"`
+	Entries       int     `json:"entries"` // -1 when the driver can not tell
+}
+
+// cacheCounters keeps the hit metrics shared by the cache drivers
+type cacheCounters struct {
+	hits, misses, sets, invalidations, evictions int64
+}
+
+func (c *cacheCounters) hit(ok bool) {
+	if ok {
+		atomic.AddInt64(&c.hits, 1)
+	} else {
+		atomic.AddInt64(&c.misses, 1)
+	}
+}
+
+func (c *cacheCounters) stats(driver string, entries int) CacheStats {
+	stats := CacheStats{
+		Driver:        driver,
+		Hits:          atomic.LoadInt64(&c.hits),
+		Misses:        atomic.LoadInt64(&c.misses),
+		Sets:          atomic.LoadInt64(&c.sets),
+		Invalidations: atomic.LoadInt64(&c.invalidations),
+		Evictions:     atomic.LoadInt64(&c.evictions),
+		Entries:       entries,
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// NewCache returns the cache driver selected by CACHE_DRIVER, memory or redis
+func NewCache(logger hclog.Logger, configs *Configurations) Cache {
+	if configs.CacheDriver == "redis" {
+		return NewRedisCache(logger, configs.RedisAddr, configs.RedisPassword)
+	}
+	return NewLRUCache(configs.CacheSize)
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	tags      []string
+	expiresAt time.Time
+}
+
+// LRUCache is an in-process cache that evicts the least recently used entry
+// once it holds size entries
+type LRUCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+	tags    map[string]map[string]struct{}
+	cacheCounters
+}
+
+func NewLRUCache(size int) *LRUCache {
+	if size <= 0 {
+		size = 1000
+	}
+	return &LRUCache{size: size, order: list.New(), entries: map[string]*list.Element{}, tags: map[string]map[string]struct{}{}}
+}
+
+func (c *LRUCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if ok && time.Now().After(element.Value.(*lruEntry).expiresAt) {
+		c.remove(element)
+		ok = false
+	}
+	c.hit(ok)
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*lruEntry).value, true
+}
+
+func (c *LRUCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	atomic.AddInt64(&c.sets, 1)
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	entry := &lruEntry{key: key, value: value, tags: tags, expiresAt: time.Now().Add(ttl)}
+	c.entries[key] = c.order.PushFront(entry)
+	for _, tag := range tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = map[string]struct{}{}
+		}
+		c.tags[tag][key] = struct{}{}
+	}
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+		atomic.AddInt64(&c.evictions, 1)
+	}
+}
+
+func (c *LRUCache) Invalidate(ctx context.Context, tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			if element, ok := c.entries[key]; ok {
+				c.remove(element)
+				atomic.AddInt64(&c.invalidations, 1)
+			}
+		}
+		delete(c.tags, tag)
+	}
+}
+
+func (c *LRUCache) Stats() CacheStats {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+	return c.stats("memory", entries)
+}
+
+// remove drops element from the list, the key map and its tag sets, c.mu must be held
+func (c *LRUCache) remove(element *list.Element) {
+	entry := element.Value.(*lruEntry)
+	c.order.Remove(element)
+	delete(c.entries, entry.key)
+	for _, tag := range entry.tags {
+		delete(c.tags[tag], entry.key)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hashicorp/go-hclog"
+)
+
+const (
+	redisCachePrefix = "cache:"
+	redisTagPrefix   = "cache:tag:"
+)
+
+// RedisCache stores the entries in any Redis compatible server so that every
+// instance of the api shares them. Tags are kept as sets of keys.
+type RedisCache struct {
+	client *redis.Client
+	logger hclog.Logger
+	cacheCounters
+}
+
+// tagScript adds a key to a tag set and only ever extends the set's TTL, a
+// short lived entry must not expire the set while longer ones remain. A set
+// without a TTL was just created by the SADD.
+var tagScript = redis.NewScript(`
+redis.call("SADD", KEYS[1], ARGV[1])
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < tonumber(ARGV[2]) then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+func NewRedisCache(logger hclog.Logger, addr string, password string) *RedisCache {
+	return &RedisCache{client: redis.NewClient(&redis.Options{Addr: addr, Password: password}), logger: logger}
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	value, err := c.client.Get(ctx, redisCachePrefix+key).Bytes()
+	if err != nil && err != redis.Nil {
+		c.logger.Error("unable to read from cache", "key", key, "error", err)
+	}
+	c.hit(err == nil)
+	return value, err == nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) {
+	atomic.AddInt64(&c.sets, 1)
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, redisCachePrefix+key, value, ttl)
+		// the tag sets expire with their longest lived entry instead of growing forever
+		for _, tag := range tags {
+			tagScript.Eval(ctx, pipe, []string{redisTagPrefix + tag}, key, ttl.Milliseconds())
+		}
+		return nil
+	})
+	if err != nil {
+		c.logger.Error("unable to write to cache", "key", key, "error", err)
+	}
+}
+
+func (c *RedisCache) Invalidate(ctx context.Context, tags ...string) {
+	for _, tag := range tags {
+		keys, err := c.client.SMembers(ctx, redisTagPrefix+tag).Result()
+		if err != nil {
+			c.logger.Error("unable to read cache tag", "tag", tag, "error", err)
+			continue
+		}
+		del := []string{redisTagPrefix + tag}
+		for _, key := range keys {
+			del = append(del, redisCachePrefix+key)
+		}
+		removed, err := c.client.Del(ctx, del...).Result()
+		if err != nil {
+			c.logger.Error("unable to invalidate cache tag", "tag", tag, "error", err)
+			continue
+		}
+		// the tag set itself is not an entry
+		if removed > 0 {
+			atomic.AddInt64(&c.invalidations, removed-1)
+		}
+	}
+}
+
+func (c *RedisCache) Stats() CacheStats {
+	return c.stats("redis", -1)
+}
@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"net/url"
+	"os"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/spf13/viper"
@@ -9,6 +12,7 @@ import (
 
 // Configurations wraps all the config variables required by the auth service
 type Configurations struct {
+	AppEnv                     string // development or production, unset only warns about missing settings
 	ServerAddress              string
 	MONGO_URI                  string
 	DBName                     string
@@ -23,9 +27,45 @@ type Configurations struct {
 	SendGridApiKey             string
 	MailVerifCodeExpiration    int // in hours
 	PassResetCodeExpiration    int // in minutes
-	MailVerifTemplateID        string
-	PassResetTemplateID        string
+	MailFrom                   string
+	MailFromName               string
+	MailDriver                 string
+	MailFile                   string
+	SMTPHost                   string
+	SMTPPort                   string
+	SMTPUsername               string
+	SMTPPassword               string
+	SMSDriver                  string
+	SMSFile                    string
+	SMSHttpUrl                 string
+	SMSHttpToken               string
+	SMSSender                  string
+	SMSCountryCode             string
+	PhoneOtpExpiration         int  // in minutes
+	RequireVerifiedEmail       bool // blocks checkout until the email is verified
 	AssetsUrl                  string
+	RecommendationInterval     int // in minutes
+	CacheDriver                string
+	CacheSize                  int // in entries
+	RedisAddr                  string
+	RedisPassword              string
+	FeedCacheTTL               int // in seconds
+	BlobDriver                 string
+	BlobLocalDir               string
+	S3Endpoint                 string
+	S3AccessKey                string
+	S3SecretKey                string
+	S3Bucket                   string
+	S3PrivateBucket            string
+	S3Region                   string
+	S3UseSSL                   bool
+	MediaGCInterval            int // in minutes
+	MediaGCGracePeriod         int // in hours
+	MediaGCDryRun              bool
+	UploadMaxSize              int64 // in bytes
+	UploadExpiration           int   // in hours
+	MediaSigningKey            string
+	MediaUrlExpiration         int // in minutes
 }
 
 // NewConfigurations returns a new Configuration object
@@ -48,11 +88,36 @@ func NewConfigurations(logger hclog.Logger) *Configurations {
 	viper.SetDefault("REFRESH_JWT_EXPIRATION", 24*60*30)
 	viper.SetDefault("MAIL_VERIFICATION_CODE_EXPIRATION", 30)
 	viper.SetDefault("PASSWORD_RESET_CODE_EXPIRATION", 15)
-	viper.SetDefault("MAIL_VERIFICATION_TEMPLATE_ID", "d-5ecbea6e38764af3b703daf03f139b48")
-	viper.SetDefault("PASSWORD_RESET_TEMPLATE_ID", "d-3fc222d11809441abaa8ed459bb44319")
+	viper.SetDefault("MAIL_FROM", "no-reply@booksland.in")
+	viper.SetDefault("MAIL_FROM_NAME", "Booksland")
+	viper.SetDefault("MAIL_FILE", "./mail.mbox")
+	viper.SetDefault("SMTP_PORT", "587")
+	viper.SetDefault("SMS_DRIVER", "console")
+	viper.SetDefault("SMS_SENDER", "BKLAND")
+	viper.SetDefault("SMS_COUNTRY_CODE", "+91")
+	viper.SetDefault("PHONE_OTP_EXPIRATION", 10)
+	viper.SetDefault("REQUIRE_VERIFIED_EMAIL", false)
 	viper.SetDefault("ASSETS_URL", "http://localhost:8000")
+	viper.SetDefault("RECOMMENDATION_INTERVAL", 60)
+	viper.SetDefault("CACHE_DRIVER", "memory")
+	viper.SetDefault("CACHE_SIZE", 1000)
+	viper.SetDefault("REDIS_ADDR", "localhost:6379")
+	viper.SetDefault("FEED_CACHE_TTL", 300)
+	viper.SetDefault("BLOB_DRIVER", "local")
+	viper.SetDefault("BLOB_LOCAL_DIR", ".")
+	viper.SetDefault("S3_ENDPOINT", "localhost:9000")
+	viper.SetDefault("S3_BUCKET", "booksland")
+	viper.SetDefault("S3_PRIVATE_BUCKET", "booksland-private")
+	viper.SetDefault("S3_REGION", "us-east-1")
+	viper.SetDefault("MEDIA_GC_INTERVAL", 24*60)
+	viper.SetDefault("MEDIA_GC_GRACE_PERIOD", 72)
+	viper.SetDefault("MEDIA_GC_DRY_RUN", true)
+	viper.SetDefault("UPLOAD_MAX_SIZE", 20*1024*1024)
+	viper.SetDefault("UPLOAD_EXPIRATION", 24)
+	viper.SetDefault("MEDIA_URL_EXPIRATION", 15)
 
 	configs := &Configurations{
+		AppEnv:                     viper.GetString("APP_ENV"),
 		ServerAddress:              viper.GetString("SERVER_ADDRESS"),
 		MONGO_URI:                  viper.GetString("MONGO_URI"),
 		DBName:                     viper.GetString("DB_NAME"),
@@ -67,9 +132,45 @@ func NewConfigurations(logger hclog.Logger) *Configurations {
 		SendGridApiKey:             viper.GetString("SENDGRID_API_KEY"),
 		MailVerifCodeExpiration:    viper.GetInt("MAIL_VERIFICATION_CODE_EXPIRATION"),
 		PassResetCodeExpiration:    viper.GetInt("PASSWORD_RESET_CODE_EXPIRATION"),
-		MailVerifTemplateID:        viper.GetString("MAIL_VERIFICATION_TEMPLATE_ID"),
-		PassResetTemplateID:        viper.GetString("PASSWORD_RESET_TEMPLATE_ID"),
+		MailFrom:                   viper.GetString("MAIL_FROM"),
+		MailFromName:               viper.GetString("MAIL_FROM_NAME"),
+		MailDriver:                 viper.GetString("MAIL_DRIVER"),
+		MailFile:                   viper.GetString("MAIL_FILE"),
+		SMTPHost:                   viper.GetString("SMTP_HOST"),
+		SMTPPort:                   viper.GetString("SMTP_PORT"),
+		SMTPUsername:               viper.GetString("SMTP_USERNAME"),
+		SMTPPassword:               viper.GetString("SMTP_PASSWORD"),
+		SMSDriver:                  viper.GetString("SMS_DRIVER"),
+		SMSFile:                    viper.GetString("SMS_FILE"),
+		SMSHttpUrl:                 viper.GetString("SMS_HTTP_URL"),
+		SMSHttpToken:               viper.GetString("SMS_HTTP_TOKEN"),
+		SMSSender:                  viper.GetString("SMS_SENDER"),
+		SMSCountryCode:             viper.GetString("SMS_COUNTRY_CODE"),
+		PhoneOtpExpiration:         viper.GetInt("PHONE_OTP_EXPIRATION"),
+		RequireVerifiedEmail:       viper.GetBool("REQUIRE_VERIFIED_EMAIL"),
 		AssetsUrl:                  viper.GetString("ASSETS_URL"),
+		RecommendationInterval:     viper.GetInt("RECOMMENDATION_INTERVAL"),
+		CacheDriver:                viper.GetString("CACHE_DRIVER"),
+		CacheSize:                  viper.GetInt("CACHE_SIZE"),
+		RedisAddr:                  viper.GetString("REDIS_ADDR"),
+		RedisPassword:              viper.GetString("REDIS_PASSWORD"),
+		FeedCacheTTL:               viper.GetInt("FEED_CACHE_TTL"),
+		BlobDriver:                 viper.GetString("BLOB_DRIVER"),
+		BlobLocalDir:               viper.GetString("BLOB_LOCAL_DIR"),
+		S3Endpoint:                 viper.GetString("S3_ENDPOINT"),
+		S3AccessKey:                viper.GetString("S3_ACCESS_KEY"),
+		S3SecretKey:                viper.GetString("S3_SECRET_KEY"),
+		S3Bucket:                   viper.GetString("S3_BUCKET"),
+		S3PrivateBucket:            viper.GetString("S3_PRIVATE_BUCKET"),
+		S3Region:                   viper.GetString("S3_REGION"),
+		S3UseSSL:                   viper.GetBool("S3_USE_SSL"),
+		MediaGCInterval:            viper.GetInt("MEDIA_GC_INTERVAL"),
+		MediaGCGracePeriod:         viper.GetInt("MEDIA_GC_GRACE_PERIOD"),
+		MediaGCDryRun:              viper.GetBool("MEDIA_GC_DRY_RUN"),
+		UploadMaxSize:              viper.GetInt64("UPLOAD_MAX_SIZE"),
+		UploadExpiration:           viper.GetInt("UPLOAD_EXPIRATION"),
+		MediaSigningKey:            viper.GetString("MEDIA_SIGNING_KEY"),
+		MediaUrlExpiration:         viper.GetInt("MEDIA_URL_EXPIRATION"),
 	}
 
 	// reading heroku provided port to handle deployment with heroku
@@ -87,6 +188,34 @@ func NewConfigurations(logger hclog.Logger) *Configurations {
 	return configs
 }
 
+// IsDevelopment tells if the server runs with APP_ENV=development
+func (config *Configurations) IsDevelopment() bool {
+	return config.AppEnv == "development"
+}
+
+// IsProduction tells if the server runs with APP_ENV=production, which turns
+// the warnings about missing settings into startup failures
+func (config *Configurations) IsProduction() bool {
+	return config.AppEnv == "production"
+}
+
+// RequireMediaSigningKey stops a production server without MEDIA_SIGNING_KEY.
+// Elsewhere a random key is used, the signed media urls then die with every
+// restart and differ between instances.
+func (config *Configurations) RequireMediaSigningKey(logger hclog.Logger) {
+	if config.MediaSigningKey != "" {
+		return
+	}
+	if config.IsProduction() {
+		logger.Error("MEDIA_SIGNING_KEY is required with APP_ENV=production")
+		os.Exit(1)
+	}
+	logger.Warn("MEDIA_SIGNING_KEY is not set, using a random key, signed media urls will not survive a restart")
+	key := make([]byte, 32)
+	rand.Read(key)
+	config.MediaSigningKey = hex.EncodeToString(key)
+}
+
 func IsUrl(str string) bool {
 	u, err := url.Parse(str)
 	return err == nil && u.Scheme != "" && u.Host != ""
@@ -3,9 +3,10 @@ package utils
 import "net/http"
 
 type RestError struct {
-	Message string `json:"message"`
-	Code    int    `json:"code"`
-	Error   string `json:"error"`
+	Message string      `json:"message"`
+	Code    int         `json:"code"`
+	Error   string      `json:"error"`
+	Details interface{} `json:"details,omitempty"`
 }
 
 func BadRequest(message string) *RestError {
@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// ImageSize is a responsive variant generated for every uploaded image, Width
+// is the most the variant can be, smaller images are never scaled up
+type ImageSize struct {
+	Name  string
+	Width int
+}
+
+var ImageSizes = []ImageSize{{"thumb", 160}, {"card", 480}, {"full", 1200}}
+
+// ImageFormats are the formats of the variants, webp needs a cgo build, see
+// imaging_webp.go
+var ImageFormats = imageFormats()
+
+func imageFormats() []string {
+	if WebpSupported {
+		return []string{"webp", "jpeg"}
+	}
+	return []string{"jpeg"}
+}
+
+const (
+	JPEG_QUALITY = 85
+	WEBP_QUALITY = 80
+)
+
+// limits checked against the image header before decoding, so a small file
+// can not expand into gigabytes of pixels
+const (
+	MAX_IMAGE_PIXELS    = 40000000
+	MAX_IMAGE_DIMENSION = 12000
+)
+
+// SafeImage is an upload decoded and encoded again, without any of the
+// metadata or trailing bytes the original carried
+type SafeImage struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+}
+
+// CheckImageSize reads the dimensions from the header and rejects the images
+// past the limits
+func CheckImageSize(data []byte) (image.Config, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return config, fmt.Errorf("unable to read the image: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return config, fmt.Errorf("the image has no pixels")
+	}
+	if config.Width > MAX_IMAGE_DIMENSION || config.Height > MAX_IMAGE_DIMENSION || config.Width*config.Height > MAX_IMAGE_PIXELS {
+		return config, fmt.Errorf("the image is %dx%d, it should be atmost %d pixels a side and %d pixels in all", config.Width, config.Height, MAX_IMAGE_DIMENSION, MAX_IMAGE_PIXELS)
+	}
+	return config, nil
+}
+
+// SanitizeImage decodes data and encodes the pixels again, which drops EXIF
+// and GPS metadata. Opaque images become JPEG, the rest PNG to keep alpha.
+func SanitizeImage(data []byte) (*SafeImage, error) {
+	if _, err := CheckImageSize(data); err != nil {
+		return nil, err
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode the image: %w", err)
+	}
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	var buf bytes.Buffer
+	safe := &SafeImage{ContentType: "image/jpeg", Ext: ".jpg", Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && !opaque.Opaque() {
+		safe.ContentType, safe.Ext = "image/png", ".png"
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: JPEG_QUALITY})
+	}
+	if err != nil {
+		return nil, err
+	}
+	safe.Data = buf.Bytes()
+	return safe, nil
+}
+
+type ImageVariant struct {
+	Size   string
+	Format string
+	Path   string
+	Width  int
+	Height int
+}
+
+// IsImageSize reports whether size names one of ImageSizes
+func IsImageSize(size string) bool {
+	for _, s := range ImageSizes {
+		if s.Name == size {
+			return true
+		}
+	}
+	return false
+}
+
+// resize scales img down to width keeping its aspect ratio
+func resize(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() <= width {
+		return img
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
+
+// flatten draws img over white, JPEG has no alpha and the encoder would turn
+// transparent pixels black
+func flatten(img image.Image) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+	bounds := img.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, bounds, img, bounds.Min, draw.Over)
+	return dst
+}
+
+func writeImage(ctx context.Context, store BlobStore, location string, img image.Image, format string) error {
+	var buf bytes.Buffer
+	var err error
+	if format == "webp" {
+		err = encodeWebp(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: JPEG_QUALITY})
+	}
+	if err != nil {
+		return err
+	}
+	return store.Put(ctx, BlobKey(location), &buf, int64(buf.Len()), "image/"+format)
+}
+
+// SaveImageVariants writes every size of the image at location in every
+// format next to it, e.g. /assets/cover-1.png gets /assets/cover-1-thumb.webp
+func SaveImageVariants(ctx context.Context, store BlobStore, location string) ([]ImageVariant, error) {
+	src, _, err := store.Get(ctx, BlobKey(location))
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	img, _, err := image.Decode(src)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode the image: %w", err)
+	}
+
+	base := strings.TrimSuffix(location, filepath.Ext(location))
+	variants := []ImageVariant{}
+	for _, size := range ImageSizes {
+		resized := resize(img, size.Width)
+		for _, format := range ImageFormats {
+			path := fmt.Sprintf("%s-%s.%s", base, size.Name, format)
+			if format == "jpeg" {
+				path = fmt.Sprintf("%s-%s.jpg", base, size.Name)
+			}
+			if err := writeImage(ctx, store, path, resized, format); err != nil {
+				RemoveImageVariants(ctx, store, variants)
+				return nil, err
+			}
+			variants = append(variants, ImageVariant{size.Name, format, path, resized.Bounds().Dx(), resized.Bounds().Dy()})
+		}
+	}
+	return variants, nil
+}
+
+// RemoveImageVariants deletes the blobs of variants, missing blobs are ignored
+func RemoveImageVariants(ctx context.Context, store BlobStore, variants []ImageVariant) {
+	for _, variant := range variants {
+		store.Delete(ctx, BlobKey(variant.Path))
+	}
+}
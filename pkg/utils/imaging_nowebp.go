@@ -0,0 +1,19 @@
+//go:build !cgo
+
+package utils
+
+import (
+	"errors"
+	"image"
+	"io"
+
+	// webp uploads can still be decoded without cgo
+	_ "golang.org/x/image/webp"
+)
+
+// WebpSupported is false without cgo, the webp encoder needs libwebp
+const WebpSupported = false
+
+func encodeWebp(w io.Writer, img image.Image) error {
+	return errors.New("webp encoding needs a cgo build")
+}
@@ -0,0 +1,19 @@
+//go:build cgo
+
+package utils
+
+import (
+	"image"
+	"io"
+
+	"github.com/chai2010/webp"
+)
+
+// WebpSupported tells if the webp variants are generated. The encoder wraps
+// libwebp, so builds with CGO_ENABLED=0 get imaging_nowebp.go instead and only
+// serve jpeg variants.
+const WebpSupported = true
+
+func encodeWebp(w io.Writer, img image.Image) error {
+	return webp.Encode(w, img, &webp.Options{Quality: WEBP_QUALITY})
+}
@@ -1,39 +1,110 @@
 package utils
 
 import (
-	"log"
-	"net/smtp"
-)
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
 
-// func sampleEmail() (string, error) {
-// }
+	"github.com/hashicorp/go-hclog"
+)
 
-func sendMail() {
+// MailMessage is a mail with a plain text body and an optional HTML body
+type MailMessage struct {
+	From    string
+	To      string
+	ToName  string
+	Subject string
+	Text    string
+	HTML    string
+}
 
-	// Sender data.
-	from := "from@gmail.com"
-	password := "<Email Password>"
+// Mailer delivers mails, the driver is picked with MAIL_DRIVER
+type Mailer interface {
+	Send(ctx context.Context, msg *MailMessage) error
+}
 
-	// Receiver email address.
-	to := []string{
-		"sender@example.com",
+// NewMailer returns the mailer of MAIL_DRIVER, smtp, sendgrid or file. Without
+// a driver the mails go to a file, which production refuses since they would
+// never reach anyone.
+func NewMailer(logger hclog.Logger, configs *Configurations) (Mailer, error) {
+	switch configs.MailDriver {
+	case "":
+		if configs.IsProduction() {
+			return nil, fmt.Errorf("MAIL_DRIVER is required with APP_ENV=production, set it to smtp, sendgrid or file")
+		}
+		logger.Warn("MAIL_DRIVER is not set, writing the mails to a file", "file", configs.MailFile)
+		return NewFileMailer(configs.MailFile), nil
+	case "file":
+		if !configs.IsDevelopment() {
+			logger.Warn("the file mail driver is active, no mail leaves the server", "file", configs.MailFile)
+		}
+		return NewFileMailer(configs.MailFile), nil
+	case "smtp":
+		if configs.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required by the smtp mail driver")
+		}
+		return NewSMTPMailer(configs.SMTPHost, configs.SMTPPort, configs.SMTPUsername, configs.SMTPPassword), nil
+	case "sendgrid":
+		if configs.SendGridApiKey == "" {
+			return nil, fmt.Errorf("SENDGRID_API_KEY is required by the sendgrid mail driver")
+		}
+		return NewSendGridMailer(configs.SendGridApiKey), nil
 	}
+	return nil, fmt.Errorf("unknown mail driver %s", configs.MailDriver)
+}
 
-	// smtp server configuration.
-	smtpHost := "smtp.gmail.com"
-	smtpPort := "587"
+// address formats a mail address with an optional display name
+func address(email string, name string) string {
+	return (&mail.Address{Name: name, Address: email}).String()
+}
 
-	// Message.
-	message := []byte("This is a test email message.")
+func quoted(s string) string {
+	var buf bytes.Buffer
+	w := quotedprintable.NewWriter(&buf)
+	w.Write([]byte(s))
+	w.Close()
+	return buf.String()
+}
 
-	// Authentication.
-	auth := smtp.PlainAuth("", from, password, smtpHost)
+// BuildMIME encodes msg as a multipart/alternative message, the form the SMTP
+// and file mailers write
+func BuildMIME(msg *MailMessage) []byte {
+	var buf bytes.Buffer
+	boundary := make([]byte, 12)
+	rand.Read(boundary)
+	headers := [][2]string{
+		{"From", msg.From},
+		{"To", address(msg.To, msg.ToName)},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", header[0], header[1])
+	}
+	if msg.HTML == "" {
+		fmt.Fprintf(&buf, "Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n%s\r\n", quoted(msg.Text))
+		return buf.Bytes()
+	}
+	b := hex.EncodeToString(boundary)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", b)
+	fmt.Fprintf(&buf, "--%s\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n%s\r\n", b, quoted(msg.Text))
+	fmt.Fprintf(&buf, "--%s\r\nContent-Type: text/html; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n%s\r\n", b, quoted(msg.HTML))
+	fmt.Fprintf(&buf, "--%s--\r\n", b)
+	return buf.Bytes()
+}
 
-	// Sending email.
-	err := smtp.SendMail(smtpHost+":"+smtpPort, auth, from, to, message)
-	if err != nil {
-		log.Println(err)
-		return
+// envelope is the bare address of From, the header may carry a display name
+func envelope(from string) string {
+	if parsed, err := mail.ParseAddress(from); err == nil {
+		return parsed.Address
 	}
-	log.Println("Email Sent Successfully!")
+	return strings.TrimSpace(from)
 }
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileMailer appends the mails to an mbox file instead of sending them, for
+// development and tests
+type FileMailer struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileMailer(path string) *FileMailer {
+	if path == "" {
+		path = "mail.mbox"
+	}
+	return &FileMailer{path: path}
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg *MailMessage) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From %s %s\n", envelope(msg.From), time.Now().UTC().Format(time.ANSIC))
+	for _, line := range bytes.Split(bytes.ReplaceAll(BuildMIME(msg), []byte("\r\n"), []byte("\n")), []byte("\n")) {
+		// mbox readers take a line starting with From as the next mail
+		if bytes.HasPrefix(line, []byte("From ")) {
+			buf.WriteByte('>')
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
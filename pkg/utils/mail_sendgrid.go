@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"time"
+)
+
+const SENDGRID_URL = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGridMailer sends through the SendGrid v3 HTTP API
+type SendGridMailer struct {
+	apiKey string
+	client *http.Client
+}
+
+func NewSendGridMailer(apiKey string) *SendGridMailer {
+	return &SendGridMailer{apiKey, &http.Client{Timeout: time.Second * 10}}
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+func (m *SendGridMailer) Send(ctx context.Context, msg *MailMessage) error {
+	from := sendGridAddress{Email: msg.From}
+	if parsed, err := mail.ParseAddress(msg.From); err == nil {
+		from = sendGridAddress{parsed.Address, parsed.Name}
+	}
+	// text/plain has to come before text/html
+	content := []sendGridContent{{"text/plain", msg.Text}}
+	if msg.HTML != "" {
+		content = append(content, sendGridContent{"text/html", msg.HTML})
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"from":             from,
+		"personalizations": []map[string]interface{}{{"to": []sendGridAddress{{msg.To, msg.ToName}}}},
+		"subject":          msg.Subject,
+		"content":          content,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, SENDGRID_URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("sendgrid responded %s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+)
+
+// SMTPMailer sends through an SMTP server, upgrading to TLS when the server
+// offers STARTTLS
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+}
+
+func NewSMTPMailer(host string, port string, username string, password string) *SMTPMailer {
+	if port == "" {
+		port = "587"
+	}
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPMailer{net.JoinHostPort(host, port), auth}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg *MailMessage) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(m.addr, m.auth, envelope(msg.From), []string{msg.To}, BuildMIME(msg))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+// SignMedia returns the signature of a private media url, a user_id binds the
+// url to that user
+func SignMedia(key string, media_id string, user_id string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "%s:%s:%d", media_id, user_id, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyMedia checks a signature made by SignMedia and that it has not expired
+func VerifyMedia(key string, media_id string, user_id string, expires int64, signature string) bool {
+	if time.Now().Unix() > expires {
+		return false
+	}
+	expected := SignMedia(key, media_id, user_id, expires)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// SMSProvider delivers text messages, the driver is picked with SMS_DRIVER
+type SMSProvider interface {
+	Send(ctx context.Context, to string, text string) error
+}
+
+// NewSMSProvider returns the provider of SMS_DRIVER, console or http
+func NewSMSProvider(logger hclog.Logger, configs *Configurations) (SMSProvider, error) {
+	switch configs.SMSDriver {
+	case "", "console":
+		if configs.SMSFile == "" {
+			return NewConsoleSMSProvider(os.Stdout), nil
+		}
+		file, err := os.OpenFile(configs.SMSFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, err
+		}
+		return NewConsoleSMSProvider(file), nil
+	case "http":
+		if configs.SMSHttpUrl == "" {
+			return nil, fmt.Errorf("SMS_HTTP_URL is required by the http sms driver")
+		}
+		return NewHttpSMSProvider(configs.SMSHttpUrl, configs.SMSHttpToken, configs.SMSSender), nil
+	}
+	return nil, fmt.Errorf("unknown sms driver %s", configs.SMSDriver)
+}
+
+// PhoneNumber turns a 10 digit number into E.164 with countryCode, numbers
+// that already start with + are kept
+func PhoneNumber(phone string, countryCode string) string {
+	if strings.HasPrefix(phone, "+") {
+		return phone
+	}
+	return countryCode + phone
+}
+
+// ConsoleSMSProvider writes the messages out instead of sending them, to the
+// console or to SMS_FILE, for development and tests
+type ConsoleSMSProvider struct {
+	out io.Writer
+	mu  sync.Mutex
+}
+
+func NewConsoleSMSProvider(out io.Writer) *ConsoleSMSProvider {
+	return &ConsoleSMSProvider{out: out}
+}
+
+func (p *ConsoleSMSProvider) Send(ctx context.Context, to string, text string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := fmt.Fprintf(p.out, "%s SMS to %s: %s\n", time.Now().Format(time.RFC3339), to, text)
+	return err
+}
+
+// HttpSMSProvider posts the messages as JSON to a gateway, {"from", "to",
+// "text"} with the token as a bearer
+type HttpSMSProvider struct {
+	url    string
+	token  string
+	sender string
+	client *http.Client
+}
+
+func NewHttpSMSProvider(url string, token string, sender string) *HttpSMSProvider {
+	return &HttpSMSProvider{url, token, sender, &http.Client{Timeout: time.Second * 10}}
+}
+
+func (p *HttpSMSProvider) Send(ctx context.Context, to string, text string) error {
+	body, err := json.Marshal(map[string]string{"from": p.sender, "to": to, "text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("sms gateway responded %s", res.Status)
+	}
+	return nil
+}
@@ -1,93 +1,157 @@
 package utils
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
-	"time"
 )
 
 const MAX_UPLOAD_SIZE = 1024 * 1024 * 2 // 2MB
 
-func saveFileinAssests(fileHeader *multipart.FileHeader) (filelocation string, err error) {
+// MAX_FORM_SIZE leaves room for the multipart boundaries and the other fields
+const MAX_FORM_SIZE = MAX_UPLOAD_SIZE + 1024*64
+
+// the directories of the uploads, only ASSETS_DIR is served publicly
+const (
+	ASSETS_DIR  = "/assets/"
+	PRIVATE_DIR = "/private/"
+)
+
+var ErrUploadTooLarge = errors.New("The uploaded image is too big. Please use an image less than 2MB in size")
+
+// UploadedFile is a file written to the blob store, or found there already
+// when Duplicate is set, then Path is empty and the media with Hash is the upload
+type UploadedFile struct {
+	Path        string
+	Hash        string // hex SHA-256 of the content
+	Size        int64
+	ContentType string
+	Width       int
+	Height      int
+	Duplicate   bool
+}
+
+// HashLookup reports whether content with the SHA-256 hash was uploaded before
+type HashLookup func(hash string) bool
+
+func saveFileinAssests(ctx context.Context, store BlobStore, dir string, fileHeader *multipart.FileHeader, seen HashLookup) (*UploadedFile, error) {
 	// truncated for brevity
 
 	// The argument to FormFile must match the name attribute
 	// of the file input on the frontend
 	file, err := fileHeader.Open()
 	if err != nil {
-		return
+		return nil, err
 	}
 	defer file.Close()
+	return SaveUpload(ctx, store, dir, file, seen)
+}
 
-	//Takeing first 521 btye to determine MIME
-	buff := make([]byte, 512)
-	_, err = file.Read(buff)
-	if err != nil {
+// assetPath names a stored upload in dir, the name the client sent is never used
+func assetPath(dir string, ext string) (string, error) {
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
 		return "", err
 	}
+	return fmt.Sprintf("%s%s%s", dir, hex.EncodeToString(name), ext), nil
+}
+
+// SaveUpload checks the uploaded image, encodes it again without its metadata
+// and writes it to the blob store, it is shared by the form uploads and the
+// resumable uploads
+func SaveUpload(ctx context.Context, store BlobStore, dir string, file io.Reader, seen HashLookup) (*UploadedFile, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
 
-	//using DetectContentType to ge filetype
-	filetype := http.DetectContentType(buff)
+	//using DetectContentType to ge filetype, it looks at the first 512 bytes
+	filetype := http.DetectContentType(data)
 	if filetype != "image/jpeg" && filetype != "image/png" && filetype != "image/jpg" && filetype != "image/webp" {
-		return "", errors.New(fmt.Sprintf("The provided file format %s is not allowed. Please upload a JPEG or PNG or WEBP image", filetype))
+		return nil, errors.New(fmt.Sprintf("The provided file format %s is not allowed. Please upload a JPEG or PNG or WEBP image", filetype))
 	}
 
-	//The file.Seek() method is used to return the pointer back to the start of the file so that io.Copy() starts from the beginning
-	_, err = file.Seek(0, io.SeekStart)
+	// the dimensions are checked before anything decodes the pixels
+	config, err := CheckImageSize(data)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	// hash what was uploaded so the same image is only stored once
+	hash := sha256.Sum256(data)
+	uploaded := &UploadedFile{Hash: hex.EncodeToString(hash[:]), ContentType: filetype, Size: int64(len(data)), Width: config.Width, Height: config.Height}
+	if seen != nil && seen(uploaded.Hash) {
+		uploaded.Duplicate = true
+		return uploaded, nil
 	}
 
-	// Create the uploads folder if it doesn't
-	// already exist
-	err = os.MkdirAll("./assets", os.ModePerm)
+	safe, err := SanitizeImage(data)
 	if err != nil {
-		return
+		return nil, err
 	}
-	// Create a new file in the uploads directory
-	filelocation = fmt.Sprintf("/assets/%s-%d%s", strings.Split(strings.ReplaceAll(fileHeader.Filename, " ", "-"), ".")[0], time.Now().UnixMilli(), filepath.Ext(fileHeader.Filename))
-	dst, err := os.Create("." + filelocation)
+	uploaded.ContentType, uploaded.Size, uploaded.Width, uploaded.Height = safe.ContentType, int64(len(safe.Data)), safe.Width, safe.Height
+	uploaded.Path, err = assetPath(dir, safe.Ext)
 	if err != nil {
-		return
+		return nil, err
 	}
-	defer dst.Close()
 
-	// Copy the uploaded file to the filesystem
+	// Copy the uploaded file to the blob store
 	// at the specified destination
-	_, err = io.Copy(dst, file)
+	err = store.Put(ctx, BlobKey(uploaded.Path), bytes.NewReader(safe.Data), uploaded.Size, uploaded.ContentType)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	log.Println("Upload successful  ->  ", "."+filelocation)
+	log.Println("Upload successful  ->  ", uploaded.Path)
 
-	return
+	return uploaded, nil
 }
 
-func UploadFile(r *http.Request, key string) (string, error) {
+// LimitBody caps the request body before the form is parsed, so an oversized
+// upload is cut off instead of being buffered to disk
+func LimitBody(w http.ResponseWriter, r *http.Request) error {
+	if r.ContentLength > MAX_FORM_SIZE {
+		return ErrUploadTooLarge
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, MAX_FORM_SIZE)
+	if err := r.ParseMultipartForm(MAX_FORM_SIZE); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return ErrUploadTooLarge
+		}
+		return err
+	}
+	return nil
+}
+
+// UploadFile stores the file of the form field key in dir, it returns nil when
+// the request has none
+func UploadFile(w http.ResponseWriter, r *http.Request, key string, store BlobStore, dir string, seen HashLookup) (*UploadedFile, error) {
 	log.Println(key)
+	if err := LimitBody(w, r); err != nil {
+		return nil, err
+	}
 	_, fileHeader, err := r.FormFile(key)
 	if err != nil || fileHeader == nil {
-		return "empty", nil
+		return nil, nil
 	}
-	filelocation, err := saveFileinAssests(fileHeader)
-	if err != nil {
-		return "", err
+	if fileHeader.Size > MAX_UPLOAD_SIZE {
+		return nil, ErrUploadTooLarge
 	}
-	return filelocation, nil
+	return saveFileinAssests(r.Context(), store, dir, fileHeader, seen)
 }
 
-func UploadFiles(r *http.Request, key string) ([]string, error) {
-	var filelocations []string
-	// 32 MB is the default used by FormFile()
-	if err := r.ParseMultipartForm(32 << 20); err != nil {
+func UploadFiles(w http.ResponseWriter, r *http.Request, key string, store BlobStore, seen HashLookup) ([]*UploadedFile, error) {
+	var uploads []*UploadedFile
+	if err := LimitBody(w, r); err != nil {
 		return nil, err
 	}
 	// Get a reference to the fileHeaders.
@@ -103,13 +167,13 @@ func UploadFiles(r *http.Request, key string) ([]string, error) {
 		// a specified value, use the http.MaxBytesReader() method
 		// before calling ParseMultipartForm()
 		if fileHeader.Size > MAX_UPLOAD_SIZE {
-			return []string{}, errors.New(fmt.Sprintf("The uploaded image is too big: %s. Please use an image less than 2MB in size", fileHeader.Filename))
+			return nil, errors.New(fmt.Sprintf("The uploaded image is too big: %s. Please use an image less than 2MB in size", fileHeader.Filename))
 		}
-		filelocation, err := saveFileinAssests(fileHeader)
+		uploaded, err := saveFileinAssests(r.Context(), store, ASSETS_DIR, fileHeader, seen)
 		if err != nil {
 			return nil, err
 		}
-		filelocations = append(filelocations, filelocation)
+		uploads = append(uploads, uploaded)
 	}
-	return filelocations, nil
+	return uploads, nil
 }